@@ -0,0 +1,247 @@
+package lighter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+const (
+	// maxBatchWrites is the Firestore limit of writes in single commit
+	maxBatchWrites = 500
+
+	defaultBulkConcurrency = 4
+)
+
+// Document is a single object with its ID written in bulk operations
+type Document struct {
+	ID     string
+	Object interface{}
+}
+
+// BulkError lists documents which failed in bulk operation. Documents
+// not listed were written successfully
+type BulkError struct {
+	Op         string
+	Collection string
+	// Errors holds the error for each failed document ID
+	Errors map[string]error
+}
+
+// Error implements the error interface
+func (e *BulkError) Error() string {
+	ids := e.FailedIDs()
+	if len(ids) == 0 {
+		return fmt.Sprintf("%s %s: no failed documents", e.Op, e.Collection)
+	}
+	return fmt.Sprintf("%s %s: %d documents failed, first %s: %v",
+		e.Op, e.Collection, len(ids), ids[0], e.Errors[ids[0]])
+}
+
+// FailedIDs returns sorted IDs of all failed documents
+func (e *BulkError) FailedIDs() []string {
+	ids := make([]string, 0, len(e.Errors))
+	for id := range e.Errors {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// SaveMany inserts or updates all documents by their IDs. Documents are written
+// in batches of up to 500 committed concurrently. Each batch is atomic so when
+// its commit fails all of its documents are reported in the returned BulkError
+func (d *Store) SaveMany(ctx context.Context, collection string, docs []*Document) error {
+	return d.options().intercept(ctx, Op{Name: "SaveMany", Collection: collection}, func(ctx context.Context) error {
+		return d.saveMany(ctx, collection, docs)
+	})
+}
+
+func (d *Store) saveMany(ctx context.Context, collection string, docs []*Document) error {
+
+	if err := validateCollection(collection); err != nil {
+		return err
+	}
+
+	bulkErr := &BulkError{Op: "SaveMany", Collection: collection, Errors: map[string]error{}}
+	writes := make([]*Document, 0, len(docs))
+	for _, doc := range docs {
+		if doc == nil {
+			continue
+		}
+		if err := validateDocArgs(collection, doc.ID); err != nil {
+			bulkErr.Errors[doc.ID] = err
+			continue
+		}
+		if doc.Object == nil {
+			bulkErr.Errors[doc.ID] = errors.New("object required")
+			continue
+		}
+		if err := d.options().beforeSave(ctx, "SaveMany", collection, doc.ID, doc.Object); err != nil {
+			bulkErr.Errors[doc.ID] = err
+			continue
+		}
+		writes = append(writes, doc)
+	}
+
+	col := d.client.Collection(collection)
+	created, err := d.createdTimes(ctx, col, writes)
+	if err != nil {
+		return wrapError("SaveMany", collection, "", err)
+	}
+
+	d.commitBatches(ctx, bulkErr, len(writes), func(b *firestore.WriteBatch, i int) string {
+		b.Set(col.Doc(writes[i].ID), d.writeData(writes[i].Object, created[writes[i].ID]))
+		return writes[i].ID
+	})
+
+	if len(bulkErr.Errors) > 0 {
+		return bulkErr
+	}
+	return nil
+
+}
+
+// createdTimes reads the creation times stored in documents written with objects
+// which have `lighter:"createdAt"` field so that SaveMany does not replace them.
+// Unlike in Save the times are not read in the same transaction as the write
+func (d *Store) createdTimes(ctx context.Context, col *firestore.CollectionRef, writes []*Document) (map[string]time.Time, error) {
+
+	fields := map[string]string{}
+	refs := make([]*firestore.DocumentRef, 0)
+	for _, w := range writes {
+		if f := createdAtField(w.Object); f != "" {
+			fields[w.ID] = f
+			refs = append(refs, col.Doc(w.ID))
+		}
+	}
+
+	times := make(map[string]time.Time, len(refs))
+	for start := 0; start < len(refs); start += maxBatchWrites {
+		end := start + maxBatchWrites
+		if end > len(refs) {
+			end = len(refs)
+		}
+		docs, err := d.client.GetAll(ctx, refs[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for _, doc := range docs {
+			if doc.Exists() {
+				times[doc.Ref.ID] = storedTime(doc, fields[doc.Ref.ID])
+				recordRead(ctx, 1)
+			}
+		}
+	}
+
+	return times, nil
+
+}
+
+// DeleteMany deletes all documents with provided IDs. Documents are deleted
+// in batches of up to 500 committed concurrently. Each batch is atomic so when
+// its commit fails all of its documents are reported in the returned BulkError
+func (d *Store) DeleteMany(ctx context.Context, collection string, ids []string) error {
+	return d.options().intercept(ctx, Op{Name: "DeleteMany", Collection: collection}, func(ctx context.Context) error {
+		return d.deleteMany(ctx, collection, ids)
+	})
+}
+
+func (d *Store) deleteMany(ctx context.Context, collection string, ids []string) error {
+
+	if err := validateCollection(collection); err != nil {
+		return err
+	}
+
+	bulkErr := &BulkError{Op: "DeleteMany", Collection: collection, Errors: map[string]error{}}
+	deletes := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if err := validateDocArgs(collection, id); err != nil {
+			bulkErr.Errors[id] = err
+			continue
+		}
+		deletes = append(deletes, id)
+	}
+
+	col := d.client.Collection(collection)
+	d.commitBatches(ctx, bulkErr, len(deletes), func(b *firestore.WriteBatch, i int) string {
+		b.Delete(col.Doc(deletes[i]))
+		return deletes[i]
+	})
+
+	if len(bulkErr.Errors) > 0 {
+		return bulkErr
+	}
+	return nil
+
+}
+
+// commitBatches splits n writes into batches and commits them concurrently
+// up to the store bulk concurrency, add is called to add i-th write to the batch
+// and returns the ID of its document
+func (d *Store) commitBatches(ctx context.Context, bulkErr *BulkError, n int, add func(b *firestore.WriteBatch, i int) string) {
+
+	concurrency := d.options().bulkConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		sem = make(chan struct{}, concurrency)
+	)
+
+	for start := 0; start < n; start += maxBatchWrites {
+		end := start + maxBatchWrites
+		if end > n {
+			end = n
+		}
+
+		batch := d.client.Batch()
+		ids := make([]string, 0, end-start)
+		for i := start; i < end; i++ {
+			ids = append(ids, add(batch, i))
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			mu.Lock()
+			for _, id := range ids {
+				bulkErr.Errors[id] = ctx.Err()
+			}
+			mu.Unlock()
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			err := d.options().retryCommit(ctx, func(ctx context.Context) error {
+				_, err := batch.Commit(ctx)
+				return err
+			})
+			if err != nil {
+				err = wrapError(bulkErr.Op, bulkErr.Collection, "", err)
+				mu.Lock()
+				for _, id := range ids {
+					bulkErr.Errors[id] = err
+				}
+				mu.Unlock()
+				return
+			}
+			recordWritten(ctx, len(ids))
+		}()
+	}
+
+	wg.Wait()
+
+}
@@ -0,0 +1,59 @@
+package lighter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkError(t *testing.T) {
+	err := &BulkError{
+		Op:         "SaveMany",
+		Collection: "test",
+		Errors: map[string]error{
+			"b1": errors.New("b"),
+			"a1": errors.New("a"),
+		},
+	}
+	assert.Equal(t, []string{"a1", "b1"}, err.FailedIDs())
+	assert.Equal(t, "SaveMany test: 2 documents failed, first a1: a", err.Error())
+}
+
+func TestSaveManyAndDeleteMany(t *testing.T) {
+
+	requireStore(t)
+
+	colName := "test_bulk"
+	ctx := context.Background()
+
+	docs := make([]*Document, 0)
+	ids := make([]string, 0)
+	for i := 0; i < maxBatchWrites+10; i++ {
+		obj := NewTestObject("John", i, 0.1)
+		docs = append(docs, &Document{ID: obj.ID, Object: obj})
+		ids = append(ids, obj.ID)
+	}
+	docs = append(docs, &Document{ID: "1invalid", Object: NewTestObject("John", 0, 0.1)})
+
+	err := store.SaveMany(ctx, colName, docs)
+	var bulkErr *BulkError
+	assert.True(t, errors.As(err, &bulkErr))
+	assert.Equal(t, []string{"1invalid"}, bulkErr.FailedIDs())
+	assert.True(t, errors.Is(bulkErr.Errors["1invalid"], ErrInvalidID))
+
+	h := &TestObjectHandler{Items: make([]*MockedStoreObject, 0)}
+	missing, err := store.GetManyByIDs(ctx, colName, ids, h)
+	assert.Nil(t, err)
+	assert.Len(t, missing, 0)
+	assert.Len(t, h.Items, len(ids))
+
+	err = store.DeleteMany(ctx, colName, ids)
+	assert.Nil(t, err)
+
+	missing, err = store.GetManyByIDs(ctx, colName, ids, h)
+	assert.Nil(t, err)
+	assert.Len(t, missing, len(ids))
+
+}
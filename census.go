@@ -0,0 +1,145 @@
+package lighter
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/status"
+)
+
+// spanPrefix is prepended to the operation name to make the span name (e.g. lighter.Save)
+const spanPrefix = "lighter."
+
+var (
+	// KeyOp tags measurements with the store operation name (e.g. Save)
+	KeyOp = tag.MustNewKey("lighter_op")
+	// KeyCollection tags measurements with the collection ID. For nested
+	// collections only the last path segment is used (e.g. orders for
+	// users/{uid}/orders) to keep the number of tag values low
+	KeyCollection = tag.MustNewKey("lighter_collection")
+)
+
+var (
+	// MeasureLatency records the duration of store operations
+	MeasureLatency = stats.Float64("lighter/latency", "Duration of store operations", stats.UnitMilliseconds)
+	// MeasureDocsRead records the number of documents loaded by store operations
+	MeasureDocsRead = stats.Int64("lighter/docs_read", "Number of documents read", stats.UnitDimensionless)
+	// MeasureDocsWritten records the number of documents written or deleted by store operations
+	MeasureDocsWritten = stats.Int64("lighter/docs_written", "Number of documents written or deleted", stats.UnitDimensionless)
+	// MeasureErrors records the number of failed store operations
+	MeasureErrors = stats.Int64("lighter/errors", "Number of failed store operations", stats.UnitDimensionless)
+)
+
+var (
+	// LatencyView is the distribution of store operation latencies
+	LatencyView = &view.View{
+		Name:        "lighter/latency",
+		Description: "Distribution of store operation latencies",
+		Measure:     MeasureLatency,
+		TagKeys:     []tag.Key{KeyOp, KeyCollection},
+		Aggregation: view.Distribution(1, 2, 5, 10, 20, 50, 100, 200, 500, 1000, 2000, 5000, 10000, 30000),
+	}
+	// DocsReadView is the total number of documents read
+	DocsReadView = &view.View{
+		Name:        "lighter/docs_read",
+		Description: "Number of documents read",
+		Measure:     MeasureDocsRead,
+		TagKeys:     []tag.Key{KeyOp, KeyCollection},
+		Aggregation: view.Sum(),
+	}
+	// DocsWrittenView is the total number of documents written or deleted
+	DocsWrittenView = &view.View{
+		Name:        "lighter/docs_written",
+		Description: "Number of documents written or deleted",
+		Measure:     MeasureDocsWritten,
+		TagKeys:     []tag.Key{KeyOp, KeyCollection},
+		Aggregation: view.Sum(),
+	}
+	// ErrorCountView is the total number of failed store operations
+	ErrorCountView = &view.View{
+		Name:        "lighter/errors",
+		Description: "Number of failed store operations",
+		Measure:     MeasureErrors,
+		TagKeys:     []tag.Key{KeyOp, KeyCollection},
+		Aggregation: view.Sum(),
+	}
+
+	// DefaultViews are all views provided by lighter, register them using view.Register
+	DefaultViews = []*view.View{LatencyView, DocsReadView, DocsWrittenView, ErrorCountView}
+)
+
+// observe runs fn in span named after op and records its latency and errors.
+// The context passed to fn is tagged with op name and collection so that
+// documents read and written by fn are recorded under the same tags
+func observe(ctx context.Context, op Op, fn func(ctx context.Context) error) error {
+
+	ctx, span := trace.StartSpan(ctx, spanPrefix+op.Name)
+	defer span.End()
+
+	span.AddAttributes(trace.StringAttribute("lighter.collection", op.Collection))
+	if op.ID != "" {
+		span.AddAttributes(trace.StringAttribute("lighter.id", op.ID))
+	}
+
+	ctx, _ = tag.New(ctx,
+		tag.Upsert(KeyOp, op.Name),
+		tag.Upsert(KeyCollection, collectionID(op.Collection)),
+	)
+
+	start := time.Now()
+	err := fn(ctx)
+
+	ms := []stats.Measurement{MeasureLatency.M(float64(time.Since(start)) / float64(time.Millisecond))}
+	if err != nil {
+		ms = append(ms, MeasureErrors.M(1))
+		span.SetStatus(spanStatus(err))
+	}
+	stats.Record(ctx, ms...)
+
+	return err
+
+}
+
+// spanStatus converts err into trace status using its gRPC code
+func spanStatus(err error) trace.Status {
+	return trace.Status{Code: int32(status.Code(err)), Message: err.Error()}
+}
+
+// collectionID returns the last segment of collection path
+func collectionID(collection string) string {
+	return collection[strings.LastIndex(collection, "/")+1:]
+}
+
+// recordRead records n documents read by the operation of ctx
+func recordRead(ctx context.Context, n int) {
+	if n > 0 {
+		stats.Record(ctx, MeasureDocsRead.M(int64(n)))
+	}
+}
+
+// recordWritten records n documents written or deleted by the operation of ctx
+func recordWritten(ctx context.Context, n int) {
+	if n > 0 {
+		stats.Record(ctx, MeasureDocsWritten.M(int64(n)))
+	}
+}
+
+// recordWrite records single document written by the operation of ctx
+// when err is nil and returns err
+func recordWrite(ctx context.Context, err error) error {
+	if err == nil {
+		recordWritten(ctx, 1)
+	}
+	return err
+}
+
+// recordResults adds the number of query results to the span of ctx
+func recordResults(ctx context.Context, n int) {
+	trace.FromContext(ctx).AddAttributes(trace.Int64Attribute("lighter.results", int64(n)))
+	recordRead(ctx, n)
+}
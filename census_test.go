@@ -0,0 +1,116 @@
+package lighter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.opencensus.io/trace"
+)
+
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []*trace.SpanData
+}
+
+func (r *spanRecorder) ExportSpan(s *trace.SpanData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, s)
+}
+
+func TestCollectionID(t *testing.T) {
+	assert.Equal(t, "orders", collectionID("orders"))
+	assert.Equal(t, "orders", collectionID(Path("users", "u1", "orders")))
+}
+
+func TestSpans(t *testing.T) {
+
+	rec := &spanRecorder{}
+	trace.RegisterExporter(rec)
+	defer trace.UnregisterExporter(rec)
+
+	ctx := context.Background()
+	ms := NewMemoryStore()
+	col := Path("users", "u1", "orders")
+	obj := NewTestObject("John", 1, 0.1)
+
+	ctx, parent := trace.StartSpan(ctx, "test", trace.WithSampler(trace.AlwaysSample()))
+	assert.Nil(t, ms.Save(ctx, col, obj.ID, obj))
+	assert.Nil(t, ms.GetByQuery(ctx, &QueryCriteria{Collection: col}, &TestObjectHandler{}))
+	assert.NotNil(t, ms.GetByID(ctx, col, "missing", &MockedStoreObject{}))
+	parent.End()
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	assert.Len(t, rec.spans, 4)
+
+	save := rec.spans[0]
+	assert.Equal(t, "lighter.Save", save.Name)
+	assert.Equal(t, col, save.Attributes["lighter.collection"])
+	assert.Equal(t, obj.ID, save.Attributes["lighter.id"])
+	assert.Equal(t, parent.SpanContext().TraceID, save.TraceID)
+
+	query := rec.spans[1]
+	assert.Equal(t, "lighter.GetByQuery", query.Name)
+	assert.Equal(t, int64(1), query.Attributes["lighter.results"])
+
+	get := rec.spans[2]
+	assert.Equal(t, "lighter.GetByID", get.Name)
+	assert.Equal(t, int32(5), get.Status.Code) // codes.NotFound
+
+}
+
+func TestViews(t *testing.T) {
+
+	assert.Nil(t, view.Register(DefaultViews...))
+	defer view.Unregister(DefaultViews...)
+
+	ctx := context.Background()
+	ms := NewMemoryStore(WithValidator("census", func(obj interface{}) error {
+		return errors.New("invalid")
+	}))
+	col := "census_views"
+
+	for _, o := range []*MockedStoreObject{NewTestObject("a", 1, 0.1), NewTestObject("b", 2, 0.2)} {
+		assert.Nil(t, ms.Save(ctx, col, o.ID, o))
+	}
+	assert.NotNil(t, ms.Save(ctx, "census", "id1", NewTestObject("c", 3, 0.3)))
+	assert.Nil(t, ms.GetByQuery(ctx, &QueryCriteria{Collection: col}, &TestObjectHandler{}))
+	assert.Nil(t, ms.DeleteAll(ctx, col, 10))
+
+	assert.Equal(t, float64(2), viewSum(t, DocsWrittenView, "Save", col))
+	assert.Equal(t, float64(2), viewSum(t, DocsWrittenView, "DeleteAll", col))
+	assert.Equal(t, float64(2), viewSum(t, DocsReadView, "GetByQuery", col))
+	assert.Equal(t, float64(1), viewSum(t, ErrorCountView, "Save", "census"))
+
+	rows, err := view.RetrieveData(LatencyView.Name)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, rows)
+
+}
+
+// viewSum returns the sum recorded by v for op and collection
+func viewSum(t *testing.T, v *view.View, op, collection string) float64 {
+	rows, err := view.RetrieveData(v.Name)
+	assert.Nil(t, err)
+	for _, row := range rows {
+		if hasTag(row.Tags, KeyOp, op) && hasTag(row.Tags, KeyCollection, collection) {
+			return row.Data.(*view.SumData).Value
+		}
+	}
+	return 0
+}
+
+func hasTag(tags []tag.Tag, k tag.Key, v string) bool {
+	for _, t := range tags {
+		if t.Key == k && t.Value == v {
+			return true
+		}
+	}
+	return false
+}
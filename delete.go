@@ -5,59 +5,283 @@ import (
 	"errors"
 	"fmt"
 
+	"cloud.google.com/go/firestore"
 	"google.golang.org/api/iterator"
 )
 
-// DeleteByID deletes stored object for a given ID
+// DeleteOption configures multi-document delete operations
+type DeleteOption func(*deleteOptions)
+
+type deleteOptions struct {
+	dryRun    bool
+	recursive bool
+	progress  func(deleted int)
+
+	// deleted is the running total of deleted documents
+	deleted int
+}
+
+// DryRun only counts the documents which would be deleted without deleting them
+func DryRun() DeleteOption {
+	return func(o *deleteOptions) {
+		o.dryRun = true
+	}
+}
+
+// Recursive also deletes all subcollections of the deleted documents
+func Recursive() DeleteOption {
+	return func(o *deleteOptions) {
+		o.recursive = true
+	}
+}
+
+// Progress registers callback invoked with the total number
+// of documents deleted so far after each committed batch
+func Progress(fn func(deleted int)) DeleteOption {
+	return func(o *deleteOptions) {
+		o.progress = fn
+	}
+}
+
+func makeDeleteOptions(opts []DeleteOption) *deleteOptions {
+	o := &deleteOptions{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+	return o
+}
+
+func (o *deleteOptions) report(n int) {
+	o.deleted += n
+	if o.progress != nil {
+		o.progress(o.deleted)
+	}
+}
+
+// DeleteByID deletes stored object for a given ID. When store uses soft delete
+// the document is only marked as deleted (see WithSoftDelete). When store uses
+// history the deleted state is archived first (see WithHistory)
 func (d *Store) DeleteByID(ctx context.Context, collection, id string) error {
+	return d.options().intercept(ctx, Op{Name: "DeleteByID", Collection: collection, ID: id, retry: d.options().writeRetry()}, func(ctx context.Context) error {
+		return recordWrite(ctx, d.deleteByID(ctx, collection, id))
+	})
+}
 
-	if !IsValidID(id) {
-		return fmt.Errorf("id must start with letter: '%s'", id)
+func (d *Store) deleteByID(ctx context.Context, collection, id string) error {
+
+	if err := validateDocArgs(collection, id); err != nil {
+		return err
 	}
 
-	if collection == "" {
-		return errors.New("collection required")
+	o := d.options()
+	if o.deleteHook(collection) != nil || o.history || o.softDelete {
+		return d.deleteInTransaction(ctx, collection, id)
 	}
 
 	_, err := d.client.Collection(collection).Doc(id).Delete(ctx)
-	return err
+	return wrapError("DeleteByID", collection, id, err)
+
+}
 
+// deleteInTransaction reads the document before deleting it in single
+// transaction so that it can be passed to BeforeDelete hook registered
+// for collection, archived when store uses history and left as it is
+// when store uses soft delete and the document is already marked as deleted
+func (d *Store) deleteInTransaction(ctx context.Context, collection, id string) error {
+
+	err := d.runTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		t := d.newTx(ctx, tx, false)
+		if err := t.deleteByID(ctx, collection, id); err != nil {
+			return err
+		}
+		return t.apply()
+	})
+
+	return wrapError("DeleteByID", collection, id, err)
+
+}
+
+// DeleteAll deletes all items in a collection. Subcollections of the deleted
+// documents are left in place unless the Recursive option is used.
+// Deleting stops when the context is cancelled
+func (d *Store) DeleteAll(ctx context.Context, collection string, batchSize int, opts ...DeleteOption) error {
+	return d.options().intercept(ctx, Op{Name: "DeleteAll", Collection: collection}, func(ctx context.Context) error {
+		return d.deleteAll(ctx, collection, batchSize, opts...)
+	})
 }
 
-// DeleteAll deletes all items in a collection
-func (d *Store) DeleteAll(ctx context.Context, collection string, batchSize int) error {
+func (d *Store) deleteAll(ctx context.Context, collection string, batchSize int, opts ...DeleteOption) error {
 
-	if collection == "" {
-		return errors.New("collection required")
+	if err := validateCollection(collection); err != nil {
+		return err
 	}
 
+	o := makeDeleteOptions(opts)
 	ref := d.client.Collection(collection)
 
-	for {
-		iter := ref.Limit(batchSize).Documents(ctx)
-		numDeleted := 0
-		batch := d.client.Batch()
+	// document listing includes missing documents which still have subcollections
+	list := listQuery(ctx, ref.Query)
+	if o.recursive {
+		list = listCollection(ctx, ref)
+	}
+
+	err := d.deleteRefs(ctx, list, batchSize, o)
+	return wrapError("DeleteAll", collection, "", err)
+
+}
+
+// DeleteByQuery deletes all documents matching query criteria in batches
+// of batchSize (max 500) and returns the number of deleted documents.
+// Use DryRun option to only count the matching documents and Recursive
+// to also delete their subcollections
+func (d *Store) DeleteByQuery(ctx context.Context, q *QueryCriteria, batchSize int, opts ...DeleteOption) (deleted int, err error) {
+	err = d.options().intercept(ctx, queryOp("DeleteByQuery", q), func(ctx context.Context) (err error) {
+		deleted, err = d.deleteByQuery(ctx, q, batchSize, opts...)
+		return err
+	})
+	return deleted, err
+}
+
+func (d *Store) deleteByQuery(ctx context.Context, q *QueryCriteria, batchSize int, opts ...DeleteOption) (deleted int, err error) {
+
+	if q == nil {
+		return 0, errors.New("query required")
+	}
+
+	if err := validateQueryCollection(q); err != nil {
+		return 0, err
+	}
+
+	sq, err := GetQueryByCriteria(d.client, q)
+	if err != nil {
+		return 0, fmt.Errorf("error building query: %w", err)
+	}
+
+	o := makeDeleteOptions(opts)
+	err = d.deleteRefs(ctx, listQuery(ctx, *sq), batchSize, o)
+	return o.deleted, wrapError("DeleteByQuery", q.Collection, "", err)
+
+}
+
+// refLister returns up to limit document references, all when limit is 0
+type refLister func(limit int) ([]*firestore.DocumentRef, error)
+
+func listQuery(ctx context.Context, q firestore.Query) refLister {
+	return func(limit int) ([]*firestore.DocumentRef, error) {
+		lq := q
+		if limit > 0 {
+			lq = q.Limit(limit)
+		}
+		iter := lq.Documents(ctx)
+		defer iter.Stop()
+
+		refs := make([]*firestore.DocumentRef, 0)
 		for {
 			doc, err := iter.Next()
+			if err == iterator.Done {
+				return refs, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			refs = append(refs, doc.Ref)
+		}
+	}
+}
+
+func listCollection(ctx context.Context, col *firestore.CollectionRef) refLister {
+	return func(limit int) ([]*firestore.DocumentRef, error) {
+		iter := col.DocumentRefs(ctx)
+		refs := make([]*firestore.DocumentRef, 0)
+		for limit == 0 || len(refs) < limit {
+			ref, err := iter.Next()
 			if err == iterator.Done {
 				break
 			}
 			if err != nil {
-				return err
+				return nil, err
+			}
+			refs = append(refs, ref)
+		}
+		return refs, nil
+	}
+}
+
+// deleteRefs deletes listed documents in batches until there are none left
+func (d *Store) deleteRefs(ctx context.Context, list refLister, batchSize int, o *deleteOptions) error {
+
+	if batchSize < 1 || batchSize > maxBatchWrites {
+		batchSize = maxBatchWrites
+	}
+
+	if o.dryRun {
+		refs, err := list(0)
+		if err != nil {
+			return err
+		}
+		for _, ref := range refs {
+			if o.recursive {
+				if err := d.deleteSubcollections(ctx, ref, batchSize, o); err != nil {
+					return err
+				}
 			}
+		}
+		o.report(len(refs))
+		return nil
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
-			batch.Delete(doc.Ref)
-			numDeleted++
+		refs, err := list(batchSize)
+		if err != nil {
+			return err
 		}
 
-		if numDeleted == 0 {
+		if len(refs) == 0 {
 			return nil
 		}
 
-		_, err := batch.Commit(ctx)
+		batch := d.client.Batch()
+		for _, ref := range refs {
+			if o.recursive {
+				if err := d.deleteSubcollections(ctx, ref, batchSize, o); err != nil {
+					return err
+				}
+			}
+			batch.Delete(ref)
+		}
+
+		err = d.options().retryCommit(ctx, func(ctx context.Context) error {
+			_, err := batch.Commit(ctx)
+			return err
+		})
 		if err != nil {
 			return err
 		}
+		recordWritten(ctx, len(refs))
+		o.report(len(refs))
 	}
 
 }
+
+// deleteSubcollections deletes all documents in all subcollections of ref
+func (d *Store) deleteSubcollections(ctx context.Context, ref *firestore.DocumentRef, batchSize int, o *deleteOptions) error {
+	iter := ref.Collections(ctx)
+	for {
+		col, err := iter.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := d.deleteRefs(ctx, listCollection(ctx, col), batchSize, o); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package lighter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteByQueryValidation(t *testing.T) {
+
+	ctx := context.Background()
+	s := &Store{}
+
+	_, err := s.DeleteByQuery(ctx, nil, 10)
+	assert.NotNil(t, err)
+
+	_, err = s.DeleteByQuery(ctx, &QueryCriteria{}, 10)
+	assert.True(t, errors.Is(err, ErrCollectionRequired))
+
+}
+
+func TestDeleteByQuery(t *testing.T) {
+
+	requireStore(t)
+
+	colName := "test_deletebyquery"
+	ctx := context.Background()
+
+	for i := 0; i < 10; i++ {
+		obj := NewTestObject("John", i, 0.1)
+		err := store.Save(ctx, colName, obj.ID, obj)
+		assert.Nil(t, err)
+	}
+
+	q := &QueryCriteria{
+		Collection: colName,
+		Criteria: []*Criterion{
+			{Property: "count", Operator: "<", Value: 7},
+		},
+	}
+
+	deleted, err := store.DeleteByQuery(ctx, q, 3, DryRun())
+	assert.Nil(t, err)
+	assert.Equal(t, 7, deleted)
+
+	deleted, err = store.DeleteByQuery(ctx, q, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 7, deleted)
+
+	h := &TestObjectHandler{Items: make([]*MockedStoreObject, 0)}
+	err = store.GetByQuery(ctx, &QueryCriteria{Collection: colName}, h)
+	assert.Nil(t, err)
+	assert.Len(t, h.Items, 3)
+
+	err = store.DeleteAll(ctx, colName, 2)
+	assert.Nil(t, err)
+
+}
+
+func TestDeleteAllRecursive(t *testing.T) {
+
+	requireStore(t)
+
+	colName := "test_deleterecursive"
+	ctx := context.Background()
+
+	parent := NewTestObject("John", 1, 0.1)
+	err := store.Save(ctx, colName, parent.ID, parent)
+	assert.Nil(t, err)
+
+	subCol := colName + "/" + parent.ID + "/children"
+	for i := 0; i < 3; i++ {
+		obj := NewTestObject("Jane", i, 0.1)
+		err := store.Save(ctx, subCol, obj.ID, obj)
+		assert.Nil(t, err)
+	}
+
+	progress := 0
+	err = store.DeleteAll(ctx, colName, 2, Recursive(), Progress(func(deleted int) {
+		progress = deleted
+	}))
+	assert.Nil(t, err)
+	assert.Equal(t, 4, progress)
+
+	h := &TestObjectHandler{Items: make([]*MockedStoreObject, 0)}
+	err = store.GetByQuery(ctx, &QueryCriteria{Collection: subCol}, h)
+	assert.Nil(t, err)
+	assert.Len(t, h.Items, 0)
+
+}
+
+func TestDeleteAllCancelled(t *testing.T) {
+
+	requireStore(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := store.DeleteAll(ctx, "test_deletecancelled", 2, Recursive())
+	assert.True(t, errors.Is(err, context.Canceled))
+
+}
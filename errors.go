@@ -0,0 +1,168 @@
+package lighter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// ErrNotFound indicates that the requested document does not exist
+	ErrNotFound = errors.New("document not found")
+	// ErrInvalidID indicates that the document ID is not a valid Firestore ID (see IsValidID)
+	ErrInvalidID = errors.New("id must start with letter and not contain /")
+	// ErrCollectionRequired indicates that the collection name was not provided
+	ErrCollectionRequired = errors.New("collection required")
+	// ErrInvalidPath indicates that the collection or document path
+	// has wrong number of segments or an empty segment
+	ErrInvalidPath = errors.New("invalid path")
+	// ErrAlreadyExists indicates that the document being created already exists
+	ErrAlreadyExists = errors.New("document already exists")
+	// ErrConflict indicates that the document was changed by another writer
+	// or the operation was aborted due to contention
+	ErrConflict = errors.New("document changed concurrently")
+	// ErrValidation indicates that the saved object failed validation,
+	// the validator error is available using errors.Unwrap
+	ErrValidation = errors.New("validation failed")
+	// ErrVetoed indicates that BeforeSave or BeforeDelete hook refused
+	// the operation, the hook error is available using errors.Unwrap
+	ErrVetoed = errors.New("operation vetoed")
+)
+
+// OpError records Firestore error along with the operation,
+// collection and document ID which caused it
+type OpError struct {
+	Op         string
+	Collection string
+	ID         string
+	Err        error
+
+	// kind is the sentinel error matching the gRPC code of Err
+	kind error
+}
+
+// Error implements the error interface
+func (e *OpError) Error() string {
+	prefix := e.Op
+	if e.Collection != "" {
+		prefix = fmt.Sprintf("%s %s", e.Op, e.Collection)
+	}
+	if e.ID != "" {
+		prefix = fmt.Sprintf("%s/%s", prefix, e.ID)
+	}
+	if e.kind != nil {
+		return fmt.Sprintf("%s: %v: %v", prefix, e.kind, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", prefix, e.Err)
+}
+
+// Unwrap returns the underlying Firestore error
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the error matches one of the lighter sentinel errors
+func (e *OpError) Is(target error) bool {
+	return e.kind != nil && e.kind == target
+}
+
+// GRPCStatus returns the gRPC status of the underlying error so that
+// status.Code and status.FromError work on wrapped errors. Errors without
+// gRPC status (e.g. validation or veto) are reported as Unknown
+func (e *OpError) GRPCStatus() *status.Status {
+	var se interface{ GRPCStatus() *status.Status }
+	if errors.As(e.Err, &se) {
+		if s := se.GRPCStatus(); s != nil {
+			return s
+		}
+	}
+	switch {
+	case errors.Is(e.Err, context.Canceled):
+		return status.New(codes.Canceled, e.Err.Error())
+	case errors.Is(e.Err, context.DeadlineExceeded):
+		return status.New(codes.DeadlineExceeded, e.Err.Error())
+	}
+	return status.New(codes.Unknown, e.Err.Error())
+}
+
+// wrapError attaches operation details to err mapping its gRPC code
+// to lighter sentinel errors
+func wrapError(op, collection, id string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if _, ok := err.(*OpError); ok {
+		return err
+	}
+
+	var kind error
+	switch status.Code(err) {
+	case codes.NotFound:
+		kind = ErrNotFound
+	case codes.AlreadyExists:
+		kind = ErrAlreadyExists
+	case codes.Aborted:
+		kind = ErrConflict
+	}
+
+	return &OpError{
+		Op:         op,
+		Collection: collection,
+		ID:         id,
+		Err:        err,
+		kind:       kind,
+	}
+}
+
+// notFoundError reports missing document the same way as Firestore NotFound status
+func notFoundError(op, collection, id string) error {
+	return wrapError(op, collection, id, status.Errorf(codes.NotFound, "no data for ID: %s", id))
+}
+
+// conflictError reports err as ErrConflict regardless of its gRPC code
+func conflictError(op, collection, id string, err error) error {
+	return &OpError{
+		Op:         op,
+		Collection: collection,
+		ID:         id,
+		Err:        err,
+		kind:       ErrConflict,
+	}
+}
+
+// validationError reports err returned by validator as ErrValidation
+func validationError(op, collection, id string, err error) error {
+	return &OpError{
+		Op:         op,
+		Collection: collection,
+		ID:         id,
+		Err:        err,
+		kind:       ErrValidation,
+	}
+}
+
+// vetoError reports err returned by lifecycle hook as ErrVetoed
+func vetoError(op, collection, id string, err error) error {
+	return &OpError{
+		Op:         op,
+		Collection: collection,
+		ID:         id,
+		Err:        err,
+		kind:       ErrVetoed,
+	}
+}
+
+// validateDocArgs checks collection and ID used to address single document
+func validateDocArgs(collection, id string) error {
+
+	if !IsValidID(id) {
+		return fmt.Errorf("%w: '%s'", ErrInvalidID, id)
+	}
+
+	return validateCollection(collection)
+
+}
@@ -0,0 +1,67 @@
+package lighter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWrapError(t *testing.T) {
+
+	assert.Nil(t, wrapError("Save", "col", "id1", nil))
+
+	list := map[codes.Code]error{
+		codes.NotFound:      ErrNotFound,
+		codes.AlreadyExists: ErrAlreadyExists,
+		codes.Aborted:       ErrConflict,
+	}
+
+	for code, kind := range list {
+		err := wrapError("Save", "col", "id1", status.Error(code, "test"))
+		assert.True(t, errors.Is(err, kind))
+		assert.Equal(t, code, status.Code(err))
+
+		var opErr *OpError
+		assert.True(t, errors.As(err, &opErr))
+		assert.Equal(t, "Save", opErr.Op)
+		assert.Equal(t, "col", opErr.Collection)
+		assert.Equal(t, "id1", opErr.ID)
+	}
+
+	err := wrapError("Save", "col", "id1", status.Error(codes.Internal, "test"))
+	assert.False(t, errors.Is(err, ErrNotFound))
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	// missing index is not a conflict
+	err = wrapError("GetByQuery", "col", "", status.Error(codes.FailedPrecondition, "The query requires an index"))
+	assert.False(t, errors.Is(err, ErrConflict))
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// errors without gRPC status are not reported as OK
+	err = wrapError("DeleteAll", "col", "", errors.New("test"))
+	assert.Equal(t, "DeleteAll col: test", err.Error())
+	assert.Equal(t, codes.Unknown, status.Code(err))
+	s, ok := status.FromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, codes.Unknown, s.Code())
+
+	err = wrapError("GetByID", "col", "id1", fmt.Errorf("timeout: %w", context.DeadlineExceeded))
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+
+	err = wrapError("RunInTransaction", "", "", status.Error(codes.AlreadyExists, "test"))
+	assert.True(t, errors.Is(err, ErrAlreadyExists))
+	assert.Equal(t, "RunInTransaction: document already exists: rpc error: code = AlreadyExists desc = test", err.Error())
+
+}
+
+func TestValidateDocArgs(t *testing.T) {
+	assert.Nil(t, validateDocArgs("col", "id1"))
+	assert.True(t, errors.Is(validateDocArgs("col", "1"), ErrInvalidID))
+	assert.True(t, errors.Is(validateDocArgs("users", "u1/orders/o1"), ErrInvalidID))
+	assert.True(t, errors.Is(validateDocArgs("", "id1"), ErrCollectionRequired))
+}
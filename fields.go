@@ -0,0 +1,283 @@
+package lighter
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+const (
+	lighterTag = "lighter"
+
+	// tagServerTimestamp sets field to Firestore server time on every write
+	tagServerTimestamp = "serverTimestamp"
+
+	// idFieldName is the Firestore name of the struct field holding document ID
+	idFieldName = "id"
+)
+
+// docField describes single struct field as seen by Firestore
+type docField struct {
+	name            string
+	index           []int
+	omitEmpty       bool
+	serverTimestamp bool
+
+	// options of the lighter struct tag (e.g. `lighter:"serverTimestamp"`)
+	lighter map[string]bool
+}
+
+// docFields returns the Firestore visible fields of struct type t
+// honoring the `firestore` struct tags and flattening embedded structs
+func docFields(t reflect.Type) []docField {
+	list := make([]docField, 0)
+	seen := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("firestore")
+		if tag == "-" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		name := parts[0]
+
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for _, f := range docFields(ft) {
+					if seen[f.name] {
+						continue
+					}
+					f.index = append([]int{i}, f.index...)
+					seen[f.name] = true
+					list = append(list, f)
+				}
+				continue
+			}
+		}
+
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		f := docField{name: name, index: []int{i}, lighter: map[string]bool{}}
+		for _, opt := range parts[1:] {
+			switch opt {
+			case "omitempty":
+				f.omitEmpty = true
+			case "serverTimestamp":
+				f.serverTimestamp = true
+			}
+		}
+		for _, opt := range strings.Split(sf.Tag.Get(lighterTag), ",") {
+			if opt != "" {
+				f.lighter[opt] = true
+			}
+		}
+		seen[name] = true
+		list = append(list, f)
+	}
+	return list
+}
+
+// isServerTimestamp reports whether field value fv should be replaced with
+// the server time, either always (lighter tag) or when zero (firestore tag)
+func (f docField) isServerTimestamp(fv reflect.Value) bool {
+	if fv.Type() != typeOfTime {
+		return false
+	}
+	if f.lighter[tagServerTimestamp] {
+		return true
+	}
+	return f.serverTimestamp && fv.Interface().(time.Time).IsZero()
+}
+
+// hasLighterTag reports whether any field of struct type t uses lighter tag option
+func hasLighterTag(t reflect.Type, opt string) bool {
+	for _, f := range docFields(t) {
+		if f.lighter[opt] {
+			return true
+		}
+	}
+	return false
+}
+
+// hasMetadataField reports whether any Firestore visible field of struct type t
+// is filled from document metadata
+func hasMetadataField(t reflect.Type) bool {
+	for _, f := range docFields(t) {
+		if f.isMetadata() {
+			return true
+		}
+	}
+	return false
+}
+
+// toWriteData prepares obj for write to Firestore. Structs using lighter
+// tags are converted into map of their top level fields where the tagged
+// fields are replaced with their Firestore sentinel values and metadata
+// fields are left out, all other objects are returned as is. When serverTime
+// is set, `lighter:"updatedAt"` fields are written as the server time too
+func toWriteData(obj interface{}, serverTime bool) interface{} {
+	v := reflect.ValueOf(obj)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return obj
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return obj
+	}
+	if !hasLighterTag(v.Type(), tagServerTimestamp) && !hasMetadataField(v.Type()) &&
+		!(serverTime && hasLighterTag(v.Type(), tagUpdatedAt)) {
+		return obj
+	}
+	return structData(v, serverTime)
+}
+
+// structData converts struct value v into map of its top level fields
+// the same way as toWriteData
+func structData(v reflect.Value, serverTime bool) map[string]interface{} {
+	m := map[string]interface{}{}
+	for _, f := range docFields(v.Type()) {
+		fv, ok := fieldByIndex(v, f.index)
+		if !ok || !fv.CanInterface() || f.isMetadata() {
+			continue
+		}
+		if f.isServerTimestamp(fv) || (serverTime && f.lighter[tagUpdatedAt] && fv.Type() == typeOfTime) {
+			m[f.name] = firestore.ServerTimestamp
+			continue
+		}
+		if f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		m[f.name] = fv.Interface()
+	}
+	return m
+}
+
+// toReplaceUpdates converts obj into updates of all its top level fields, so that
+// Update replaces the stored fields the same way as Set. Struct fields left out
+// by omitempty are deleted, the field named keep is left as stored. Fields of
+// stored document which are missing in map obj are kept
+func toReplaceUpdates(obj interface{}, serverTime bool, keep string) []firestore.Update {
+	v := reflect.ValueOf(obj)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	fields := map[string]interface{}{}
+	switch {
+	case v.Kind() == reflect.Struct:
+		fields = structData(v, serverTime)
+		for _, f := range docFields(v.Type()) {
+			if _, ok := fields[f.name]; !ok && !f.isMetadata() {
+				fields[f.name] = firestore.Delete
+			}
+		}
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		for _, k := range v.MapKeys() {
+			fields[k.String()] = v.MapIndex(k).Interface()
+		}
+	}
+	delete(fields, keep)
+
+	updates := make([]firestore.Update, 0, len(fields))
+	for name, val := range fields {
+		updates = append(updates, firestore.Update{FieldPath: firestore.FieldPath{name}, Value: val})
+	}
+	sort.Slice(updates, func(i, j int) bool {
+		return updates[i].FieldPath[0] < updates[j].FieldPath[0]
+	})
+	return updates
+}
+
+// setDocID sets the `firestore:"id"` string field of the struct obj points to
+func setDocID(obj interface{}, id string) {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return
+	}
+	v = v.Elem()
+
+	for _, f := range docFields(v.Type()) {
+		if f.name != idFieldName {
+			continue
+		}
+		fv, err := settableField(v, f.index)
+		if err == nil && fv.CanSet() && fv.Kind() == reflect.String {
+			fv.SetString(id)
+		}
+		return
+	}
+}
+
+// fieldByIndex is reflect.Value.FieldByIndex which does not panic on nil
+// embedded pointers
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// isEmptyValue follows the omitempty semantics of Firestore
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	if v.Type() == typeOfTime {
+		return v.Interface().(time.Time).IsZero()
+	}
+	return false
+}
+
+// settableField returns struct field allocating nil embedded pointers
+func settableField(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("cannot set embedded pointer to unexported struct: %v", v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
@@ -0,0 +1,104 @@
+package lighter
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/stretchr/testify/assert"
+)
+
+type timestampedObject struct {
+	ID      string    `firestore:"id"`
+	Name    string    `firestore:"name,omitempty"`
+	Skip    string    `firestore:"-"`
+	Updated time.Time `firestore:"updated" lighter:"serverTimestamp"`
+	Created time.Time `firestore:"created,serverTimestamp"`
+}
+
+func TestDocFields(t *testing.T) {
+	fields := docFields(reflect.TypeOf(timestampedObject{}))
+	assert.Len(t, fields, 4)
+	assert.Equal(t, "id", fields[0].name)
+	assert.True(t, fields[1].omitEmpty)
+	assert.True(t, fields[2].lighter[tagServerTimestamp])
+	assert.True(t, fields[3].serverTimestamp)
+}
+
+func TestToWriteData(t *testing.T) {
+
+	// objects without lighter tags are written as is
+	obj := NewTestObject("John", 40, 2.75)
+	assert.Equal(t, obj, toWriteData(obj, false))
+
+	now := time.Now()
+	data := toWriteData(&timestampedObject{ID: "id1", Skip: "skip", Updated: now, Created: now}, false)
+	m, ok := data.(map[string]interface{})
+	assert.True(t, ok)
+	assert.Equal(t, "id1", m["id"])
+	assert.Equal(t, firestore.ServerTimestamp, m["updated"])
+	assert.Equal(t, now, m["created"])
+	assert.NotContains(t, m, "name")
+	assert.NotContains(t, m, "Skip")
+
+	m = toWriteData(&timestampedObject{ID: "id1"}, false).(map[string]interface{})
+	assert.Equal(t, firestore.ServerTimestamp, m["created"])
+
+}
+
+func TestToReplaceUpdates(t *testing.T) {
+
+	now := time.Now()
+	updates := toReplaceUpdates(&timestampedObject{ID: "id1", Skip: "skip", Created: now}, false, "created")
+	assert.Equal(t, []firestore.Update{
+		{FieldPath: firestore.FieldPath{"id"}, Value: "id1"},
+		{FieldPath: firestore.FieldPath{"name"}, Value: firestore.Delete},
+		{FieldPath: firestore.FieldPath{"updated"}, Value: firestore.ServerTimestamp},
+	}, updates)
+
+	updates = toReplaceUpdates(map[string]interface{}{"b": 2, "a.b": 1}, false, "")
+	assert.Equal(t, []firestore.Update{
+		{FieldPath: firestore.FieldPath{"a.b"}, Value: 1},
+		{FieldPath: firestore.FieldPath{"b"}, Value: 2},
+	}, updates)
+
+	assert.Empty(t, toReplaceUpdates((*timestampedObject)(nil), false, ""))
+
+}
+
+func TestMemoryServerTimestamp(t *testing.T) {
+
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	doc, err := toMemoryDoc(&timestampedObject{ID: "id1", Updated: time.Unix(0, 0)}, now)
+	assert.Nil(t, err)
+	assert.Equal(t, now, doc["updated"])
+	assert.Equal(t, now, doc["created"])
+
+	// store clock is used as the server time
+	clock := newTestClock()
+	ms := NewMemoryStore(WithClock(clock.Now))
+	assert.Nil(t, ms.Save(context.Background(), "col", "id1", &timestampedObject{ID: "id1"}))
+	assert.Equal(t, clock.Now(), ms.collections["col"]["id1"]["updated"])
+
+}
+
+func TestSetDocID(t *testing.T) {
+
+	obj := &MockedStoreObject{}
+	setDocID(obj, "id1")
+	assert.Equal(t, "id1", obj.ID)
+
+	// values and objects without id field are left as is
+	val := MockedStoreObject{}
+	setDocID(val, "id1")
+	assert.Equal(t, "", val.ID)
+
+	other := &struct {
+		Key string `firestore:"key"`
+	}{}
+	setDocID(other, "id1")
+	assert.Equal(t, "", other.Key)
+
+}
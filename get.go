@@ -2,8 +2,8 @@ package lighter
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	"google.golang.org/api/iterator"
@@ -11,28 +11,64 @@ import (
 
 // GetByID returns stored object for given ID
 func (d *Store) GetByID(ctx context.Context, collection, id string, in interface{}) error {
+	return d.options().intercept(ctx, Op{Name: "GetByID", Collection: collection, ID: id, retry: retryIdempotent}, func(ctx context.Context) error {
+		_, err := d.getByID(ctx, "GetByID", collection, id, in)
+		return err
+	})
+}
 
-	if !IsValidID(id) {
-		return fmt.Errorf("id must start with letter: '%s'", id)
-	}
+// GetByIDWithUpdateTime returns stored object for given ID along with the time
+// it was last updated. Pass that time to SaveIfUnchanged to avoid lost updates
+func (d *Store) GetByIDWithUpdateTime(ctx context.Context, collection, id string, in interface{}) (updateTime time.Time, err error) {
+	err = d.options().intercept(ctx, Op{Name: "GetByIDWithUpdateTime", Collection: collection, ID: id, retry: retryIdempotent}, func(ctx context.Context) error {
+		doc, err := d.getByID(ctx, "GetByIDWithUpdateTime", collection, id, in)
+		if err == nil {
+			updateTime = doc.UpdateTime
+		}
+		return err
+	})
+	return updateTime, err
+}
 
-	if collection == "" {
-		return errors.New("collection required")
+func (d *Store) getByID(ctx context.Context, op, collection, id string, in interface{}) (*firestore.DocumentSnapshot, error) {
+
+	if err := validateDocArgs(collection, id); err != nil {
+		return nil, err
 	}
 
 	doc, err := d.client.Collection(collection).Doc(id).Get(ctx)
 	if err != nil {
-		return err
+		return nil, wrapError(op, collection, id, err)
 	}
 
-	if doc == nil {
-		return fmt.Errorf("no data for ID: %s", id)
+	if err := d.loadDoc(ctx, op, collection, id, doc, in); err != nil {
+		return nil, err
 	}
+	return doc, nil
+
+}
+
+// loadDoc loads data and metadata of doc into in,
+// fails with ErrNotFound when doc does not exist or is hidden
+func (d *Store) loadDoc(ctx context.Context, op, collection, id string, doc *firestore.DocumentSnapshot, in interface{}) error {
 
-	if e := doc.DataTo(in); err != nil {
-		return fmt.Errorf("error parsing data: %v", e)
+	if doc == nil || !doc.Exists() || d.isHidden(doc, false) {
+		return notFoundError(op, collection, id)
 	}
 
+	if err := doc.DataTo(in); err != nil {
+		return fmt.Errorf("error parsing data: %v", err)
+	}
+
+	if err := applyMetadata(in, snapshotMetadata(doc)); err != nil {
+		return fmt.Errorf("error setting metadata: %v", err)
+	}
+
+	if err := afterLoad(ctx, in); err != nil {
+		return wrapError(op, collection, id, err)
+	}
+
+	recordRead(ctx, 1)
 	return nil
 
 }
@@ -45,11 +81,25 @@ type ResultHandler interface {
 	Append(item interface{})
 }
 
+// PathResultHandler can be implemented by ResultHandler to also receive
+// the path of each loaded document (e.g. users/{uid}/orders/{oid}),
+// which is useful to learn the parent of collection group query results
+type PathResultHandler interface {
+	ResultHandler
+	// AppendWithPath is called instead of Append with the document path
+	AppendWithPath(item interface{}, path string)
+}
+
 // QueryCriteria defines the Firestore query query
 type QueryCriteria struct {
 	Collection string
-	Criteria   []*Criterion
-	OrderBy    *Order
+	// CollectionGroup queries all collections with the Collection ID
+	// regardless of their parent document (e.g. all orders of all users)
+	CollectionGroup bool
+	// IncludeDeleted includes documents marked as deleted when store uses soft delete
+	IncludeDeleted bool
+	Criteria       []*Criterion
+	OrderBy        *Order
 }
 
 // Order defines a single Firestore property sort order
@@ -68,6 +118,12 @@ type Criterion struct {
 
 // GetByQuery allows for filtered query using QueryHandler
 func (d *Store) GetByQuery(ctx context.Context, q *QueryCriteria, h ResultHandler) error {
+	return d.options().intercept(ctx, queryOp("GetByQuery", q), func(ctx context.Context) error {
+		return d.getByQuery(ctx, q, h)
+	})
+}
+
+func (d *Store) getByQuery(ctx context.Context, q *QueryCriteria, h ResultHandler) error {
 
 	if q == nil {
 		return fmt.Errorf("query required")
@@ -79,13 +135,18 @@ func (d *Store) GetByQuery(ctx context.Context, q *QueryCriteria, h ResultHandle
 
 	sq, err := GetQueryByCriteria(d.client, q)
 	if err != nil {
-		return fmt.Errorf("error building query: %v", err)
+		return fmt.Errorf("error building query: %w", err)
 	}
 
 	docs := sq.Documents(ctx)
 	defer docs.Stop()
 
-	return HandleResults(ctx, docs, h)
+	n, err := handleResults(ctx, docs, h, func(doc *firestore.DocumentSnapshot) bool {
+		return d.isHidden(doc, q.IncludeDeleted)
+	})
+	recordResults(ctx, n)
+
+	return wrapError("GetByQuery", q.Collection, "", err)
 
 }
 
@@ -100,7 +161,14 @@ func GetQueryByCriteria(c *firestore.Client, q *QueryCriteria) (query *firestore
 		return nil, fmt.Errorf("query required")
 	}
 
+	if err := validateQueryCollection(q); err != nil {
+		return nil, err
+	}
+
 	sq := c.Collection(q.Collection).Query
+	if q.CollectionGroup {
+		sq = c.CollectionGroup(q.Collection).Query
+	}
 
 	if q.Criteria != nil {
 		for _, c := range q.Criteria {
@@ -122,13 +190,20 @@ func GetQueryByCriteria(c *firestore.Client, q *QueryCriteria) (query *firestore
 
 // HandleResults allows for filtered query using QueryHandler
 func HandleResults(ctx context.Context, docs *firestore.DocumentIterator, h ResultHandler) error {
+	_, err := handleResults(ctx, docs, h, nil)
+	return err
+}
+
+// handleResults appends all documents for which skip is not true to handler
+// results and returns the number of appended documents
+func handleResults(ctx context.Context, docs *firestore.DocumentIterator, h ResultHandler, skip func(*firestore.DocumentSnapshot) bool) (n int, err error) {
 
 	if docs == nil {
-		return fmt.Errorf("doc iterator required")
+		return 0, fmt.Errorf("doc iterator required")
 	}
 
 	if h == nil {
-		return fmt.Errorf("handler required")
+		return 0, fmt.Errorf("handler required")
 	}
 
 	for {
@@ -137,16 +212,45 @@ func HandleResults(ctx context.Context, docs *firestore.DocumentIterator, h Resu
 			break
 		}
 		if e != nil {
-			return e
+			return n, e
 		}
 
-		item := h.MakeNew()
-		if e := d.DataTo(&item); e != nil {
-			return e
+		if skip != nil && skip(d) {
+			continue
 		}
-		h.Append(item)
+
+		if e := appendResult(ctx, d, h); e != nil {
+			return n, e
+		}
+		n++
 	}
 
+	return n, nil
+
+}
+
+// appendResult loads document into new handler item and appends it to results
+func appendResult(ctx context.Context, doc *firestore.DocumentSnapshot, h ResultHandler) error {
+	item := h.MakeNew()
+	if err := doc.DataTo(&item); err != nil {
+		return err
+	}
+	m := snapshotMetadata(doc)
+	if err := applyMetadata(item, m); err != nil {
+		return err
+	}
+	if err := afterLoad(ctx, item); err != nil {
+		return err
+	}
+	appendItem(h, item, m.Path)
 	return nil
+}
 
+// appendItem appends item to handler results passing its path when supported
+func appendItem(h ResultHandler, item interface{}, path string) {
+	if ph, ok := h.(PathResultHandler); ok {
+		ph.AppendWithPath(item, path)
+		return
+	}
+	h.Append(item)
 }
@@ -2,6 +2,7 @@ package lighter
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -9,6 +10,8 @@ import (
 
 func TestGetByID(t *testing.T) {
 
+	requireStore(t)
+
 	colName := "test_get"
 	ctx := context.Background()
 
@@ -28,6 +31,8 @@ func TestGetByID(t *testing.T) {
 
 func TestQuerySort(t *testing.T) {
 
+	requireStore(t)
+
 	colName := "test_sortcol"
 	ctx := context.Background()
 	err := store.DeleteAll(ctx, colName, 1)
@@ -72,6 +77,8 @@ func TestQuerySort(t *testing.T) {
 
 func TestGetByQuery(t *testing.T) {
 
+	requireStore(t)
+
 	colName := "test_getcriterion"
 	ctx := context.Background()
 
@@ -118,14 +125,66 @@ func TestGetByQuery(t *testing.T) {
 
 func TestNulData(t *testing.T) {
 
+	requireStore(t)
+
 	colName := "test_getnil"
 	ctx := context.Background()
 
 	obj := &MockedStoreObject{}
 	err := store.GetByID(ctx, colName, "invalidObjectID", obj)
 	assert.NotNil(t, err)
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+}
+
+func TestGetByPath(t *testing.T) {
+
+	requireStore(t)
+
+	ctx := context.Background()
+	user := NewTestObject("John", 1, 0.1)
+	col := Path("test_users", user.ID, "orders")
+
+	order := NewTestObject("Order", 1, 9.99)
+	err := store.Save(ctx, col, order.ID, order)
+	assert.Nil(t, err)
+
+	h := &TestObjectHandler{Items: make([]*MockedStoreObject, 0)}
+	err = store.GetByQuery(ctx, &QueryCriteria{Collection: col}, h)
+	assert.Nil(t, err)
+	assert.Len(t, h.Items, 1)
+
+	err = store.DeleteAll(ctx, "test_users", 10, Recursive())
+	assert.Nil(t, err)
+
+	err = store.GetByID(ctx, col, order.ID, &MockedStoreObject{})
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+}
+
+func TestCollectionGroupQuery(t *testing.T) {
+
+	requireStore(t)
+
+	ctx := context.Background()
+	group := "test_group_orders"
+
+	paths := make([]string, 0)
+	for _, uid := range []string{"u1", "u2"} {
+		col := Path("test_group_users", uid, group)
+		obj := NewTestObject("Order", 1, 0.1)
+		err := store.Save(ctx, col, obj.ID, obj)
+		assert.Nil(t, err)
+		paths = append(paths, Path(col, obj.ID))
+	}
+
+	h := &PathObjectHandler{}
+	err := store.GetByQuery(ctx, &QueryCriteria{Collection: group, CollectionGroup: true}, h)
+	assert.Nil(t, err)
+	assert.Len(t, h.Items, 2)
+	assert.ElementsMatch(t, paths, h.Paths)
 
-	err = store.Close()
+	err = store.DeleteAll(ctx, "test_group_users", 10, Recursive())
 	assert.Nil(t, err)
 
 }
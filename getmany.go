@@ -0,0 +1,75 @@
+package lighter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+)
+
+// getManyBatchSize is the max number of documents requested in single GetAll call
+const getManyBatchSize = 100
+
+// GetManyByIDs loads stored objects for all provided IDs in as few round trips
+// as possible. Found objects are appended to handler in the order of ids,
+// IDs of documents which do not exist (or are marked as deleted) are returned in missing
+func (d *Store) GetManyByIDs(ctx context.Context, collection string, ids []string, h ResultHandler) (missing []string, err error) {
+	err = d.options().intercept(ctx, Op{Name: "GetManyByIDs", Collection: collection}, func(ctx context.Context) (err error) {
+		missing, err = d.getManyByIDs(ctx, collection, ids, h)
+		return err
+	})
+	return missing, err
+}
+
+func (d *Store) getManyByIDs(ctx context.Context, collection string, ids []string, h ResultHandler) (missing []string, err error) {
+
+	if err := validateCollection(collection); err != nil {
+		return nil, err
+	}
+
+	if h == nil {
+		return nil, errors.New("handler required")
+	}
+
+	for _, id := range ids {
+		if !IsValidID(id) {
+			return nil, fmt.Errorf("%w: '%s'", ErrInvalidID, id)
+		}
+	}
+
+	col := d.client.Collection(collection)
+	missing = make([]string, 0)
+
+	for start := 0; start < len(ids); start += getManyBatchSize {
+		end := start + getManyBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		refs := make([]*firestore.DocumentRef, 0, end-start)
+		for _, id := range ids[start:end] {
+			refs = append(refs, col.Doc(id))
+		}
+
+		// GetAll returns snapshots in the same order as refs
+		docs, err := d.client.GetAll(ctx, refs)
+		if err != nil {
+			return nil, wrapError("GetManyByIDs", collection, "", err)
+		}
+
+		for i, doc := range docs {
+			if !doc.Exists() || d.isHidden(doc, false) {
+				missing = append(missing, ids[start+i])
+				continue
+			}
+			if err := appendResult(ctx, doc, h); err != nil {
+				return nil, fmt.Errorf("error parsing data for ID %s: %v", ids[start+i], err)
+			}
+		}
+	}
+
+	recordRead(ctx, len(ids)-len(missing))
+	return missing, nil
+
+}
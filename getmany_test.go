@@ -0,0 +1,61 @@
+package lighter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetManyByIDsValidation(t *testing.T) {
+
+	ctx := context.Background()
+	s := &Store{}
+	h := &TestObjectHandler{}
+
+	_, err := s.GetManyByIDs(ctx, "", []string{"a1"}, h)
+	assert.True(t, errors.Is(err, ErrCollectionRequired))
+
+	_, err = s.GetManyByIDs(ctx, "test", []string{"a1", "1"}, h)
+	assert.True(t, errors.Is(err, ErrInvalidID))
+
+	_, err = s.GetManyByIDs(ctx, "test", []string{"a1"}, nil)
+	assert.NotNil(t, err)
+
+}
+
+func TestGetManyByIDs(t *testing.T) {
+
+	requireStore(t)
+
+	colName := "test_getmany"
+	ctx := context.Background()
+
+	ids := make([]string, 0)
+	for i := 0; i < getManyBatchSize+5; i++ {
+		obj := NewTestObject("John", i, 0.1)
+		err := store.Save(ctx, colName, obj.ID, obj)
+		assert.Nil(t, err)
+		ids = append(ids, obj.ID)
+	}
+
+	// reverse order and add missing ID in the middle
+	query := []string{ids[len(ids)-1], "missingID"}
+	for i := len(ids) - 2; i >= 0; i-- {
+		query = append(query, ids[i])
+	}
+
+	h := &TestObjectHandler{Items: make([]*MockedStoreObject, 0)}
+	missing, err := store.GetManyByIDs(ctx, colName, query, h)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"missingID"}, missing)
+	assert.Len(t, h.Items, len(ids))
+	for i, item := range h.Items {
+		assert.Equal(t, len(ids)-1-i, item.Count)
+	}
+
+	err = store.DeleteAll(ctx, colName, 50)
+	assert.Nil(t, err)
+
+}
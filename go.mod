@@ -7,5 +7,7 @@ require (
 	cloud.google.com/go/firestore v1.1.0
 	github.com/satori/go.uuid v1.2.0
 	github.com/stretchr/testify v1.4.0
+	go.opencensus.io v0.22.0
 	google.golang.org/api v0.14.0
+	google.golang.org/grpc v1.21.1
 )
@@ -0,0 +1,269 @@
+package lighter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// HistoryCollection is the subcollection holding previous revisions
+// of documents written by store using history (see WithHistory)
+const HistoryCollection = "_history"
+
+const (
+	revisionField         = "_rev"
+	revisionUpdatedField  = "_updatedAt"
+	revisionArchivedField = "_archivedAt"
+	revisionOpField       = "_op"
+
+	// historyPruneLimit is the max number of revisions deleted in single write
+	// so that lowering max revisions does not exceed the transaction limits
+	historyPruneLimit = 100
+)
+
+// Revision describes previous state of document archived by store using history
+type Revision struct {
+	// Rev is the revision number, starting with 1 for the oldest one
+	Rev int64
+	// Op is the operation which replaced this state (e.g. Save or DeleteByID)
+	Op string
+	// UpdatedAt is the time this state was written
+	UpdatedAt time.Time
+	// ArchivedAt is the time this state was replaced
+	ArchivedAt time.Time
+}
+
+// revisionID returns the ID of the history document holding revision rev
+func revisionID(rev int64) string {
+	return fmt.Sprintf("rev%d", rev)
+}
+
+// archive copies the stored state of doc into its history as the next revision
+// and prunes revisions over the max. Must be called before any writes in tx
+func (d *Store) archive(tx *firestore.Transaction, doc *firestore.DocumentSnapshot, op string) error {
+	a, err := d.prepareArchive(tx, doc, op)
+	if err != nil {
+		return err
+	}
+	return a.write(tx)
+}
+
+// archived is the revision prepared by prepareArchive waiting to be written
+type archived struct {
+	ref    *firestore.DocumentRef
+	data   map[string]interface{}
+	pruned []*firestore.DocumentSnapshot
+}
+
+// prepareArchive reads the history of doc in tx and returns its next revision
+// along with the revisions to prune, nil when doc does not exist
+func (d *Store) prepareArchive(tx *firestore.Transaction, doc *firestore.DocumentSnapshot, op string) (*archived, error) {
+
+	if doc == nil || !doc.Exists() {
+		return nil, nil
+	}
+
+	hist := doc.Ref.Collection(HistoryCollection)
+	last, err := tx.Documents(hist.OrderBy(revisionField, firestore.Desc).Limit(1)).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	rev := int64(1)
+	if len(last) > 0 {
+		if v, err := last[0].DataAt(revisionField); err == nil {
+			if n, ok := v.(int64); ok {
+				rev = n + 1
+			}
+		}
+	}
+
+	var pruned []*firestore.DocumentSnapshot
+	if max := int64(d.options().maxRevisions); max > 0 && rev > max {
+		q := hist.Where(revisionField, "<=", rev-max).Select().Limit(historyPruneLimit)
+		if pruned, err = tx.Documents(q).GetAll(); err != nil {
+			return nil, err
+		}
+	}
+
+	data := doc.Data()
+	data[revisionField] = rev
+	data[revisionUpdatedField] = doc.UpdateTime
+	data[revisionArchivedField] = firestore.ServerTimestamp
+	data[revisionOpField] = op
+
+	return &archived{ref: hist.Doc(revisionID(rev)), data: data, pruned: pruned}, nil
+
+}
+
+// write adds the archived revision to tx and deletes the pruned ones
+func (a *archived) write(tx *firestore.Transaction) error {
+
+	if a == nil {
+		return nil
+	}
+
+	if err := tx.Create(a.ref, a.data); err != nil {
+		return err
+	}
+
+	for _, p := range a.pruned {
+		if err := tx.Delete(p.Ref); err != nil {
+			return err
+		}
+	}
+
+	return nil
+
+}
+
+// loadRevision loads the document state held by revision doc into obj
+// leaving out the revision bookkeeping fields. Maps get the remaining data,
+// structs are loaded by Firestore which ignores fields they do not have
+func loadRevision(doc *firestore.DocumentSnapshot, obj interface{}) error {
+	data := doc.Data()
+	for _, f := range []string{revisionField, revisionUpdatedField, revisionArchivedField, revisionOpField} {
+		delete(data, f)
+	}
+
+	switch m := obj.(type) {
+	case map[string]interface{}:
+		for k := range m {
+			delete(m, k)
+		}
+		for k, v := range data {
+			m[k] = v
+		}
+		return nil
+	case *map[string]interface{}:
+		*m = data
+		return nil
+	}
+	return doc.DataTo(obj)
+}
+
+// ListRevisions returns the archived revisions of document, oldest first
+func (d *Store) ListRevisions(ctx context.Context, collection, id string) ([]*Revision, error) {
+	var list []*Revision
+	err := d.options().intercept(ctx, Op{Name: "ListRevisions", Collection: collection, ID: id, retry: retryIdempotent}, func(ctx context.Context) (err error) {
+		list, err = d.listRevisions(ctx, collection, id)
+		return err
+	})
+	return list, err
+}
+
+func (d *Store) listRevisions(ctx context.Context, collection, id string) ([]*Revision, error) {
+
+	if err := validateDocArgs(collection, id); err != nil {
+		return nil, err
+	}
+
+	q := d.client.Collection(collection).Doc(id).Collection(HistoryCollection).
+		Select(revisionField, revisionOpField, revisionUpdatedField, revisionArchivedField).
+		OrderBy(revisionField, firestore.Asc)
+
+	docs, err := q.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, wrapError("ListRevisions", collection, id, err)
+	}
+
+	list := make([]*Revision, 0, len(docs))
+	for _, doc := range docs {
+		r := &Revision{
+			UpdatedAt:  storedTime(doc, revisionUpdatedField),
+			ArchivedAt: storedTime(doc, revisionArchivedField),
+		}
+		if v, err := doc.DataAt(revisionField); err == nil {
+			r.Rev, _ = v.(int64)
+		}
+		if v, err := doc.DataAt(revisionOpField); err == nil {
+			r.Op, _ = v.(string)
+		}
+		list = append(list, r)
+	}
+
+	recordRead(ctx, len(docs))
+	return list, nil
+
+}
+
+// GetRevision loads revision rev of document into in.
+// Fails with ErrNotFound when there is no such revision
+func (d *Store) GetRevision(ctx context.Context, collection, id string, rev int64, in interface{}) error {
+	return d.options().intercept(ctx, Op{Name: "GetRevision", Collection: collection, ID: id, retry: retryIdempotent}, func(ctx context.Context) error {
+		return d.getRevision(ctx, collection, id, rev, in)
+	})
+}
+
+func (d *Store) getRevision(ctx context.Context, collection, id string, rev int64, in interface{}) error {
+
+	if err := validateDocArgs(collection, id); err != nil {
+		return err
+	}
+
+	doc, err := d.client.Collection(collection).Doc(id).Collection(HistoryCollection).Doc(revisionID(rev)).Get(ctx)
+	if err != nil {
+		return wrapError("GetRevision", collection, id, err)
+	}
+
+	if err := loadRevision(doc, in); err != nil {
+		return fmt.Errorf("error parsing data: %v", err)
+	}
+
+	err = applyMetadata(in, &Metadata{
+		ID:         id,
+		Path:       Path(collection, id),
+		UpdateTime: storedTime(doc, revisionUpdatedField),
+		ReadTime:   doc.ReadTime,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := afterLoad(ctx, in); err != nil {
+		return wrapError("GetRevision", collection, id, err)
+	}
+
+	recordRead(ctx, 1)
+	return nil
+
+}
+
+// RevertTo replaces document with the state of its revision rev. The revision is
+// loaded into obj which is then saved the same way as by Save, so it is validated,
+// passed to BeforeSave and timestamped. When store uses history the replaced state
+// is archived as new revision first. Fails with ErrNotFound when there is no such revision
+func (d *Store) RevertTo(ctx context.Context, collection, id string, rev int64, obj interface{}) error {
+	return d.options().intercept(ctx, Op{Name: "RevertTo", Collection: collection, ID: id, retry: retryNonIdempotent}, func(ctx context.Context) error {
+		return d.revertTo(ctx, collection, id, rev, obj)
+	})
+}
+
+func (d *Store) revertTo(ctx context.Context, collection, id string, rev int64, obj interface{}) error {
+
+	if obj == nil {
+		return errors.New("object required")
+	}
+
+	if err := validateDocArgs(collection, id); err != nil {
+		return err
+	}
+
+	revRef := d.client.Collection(collection).Doc(id).Collection(HistoryCollection).Doc(revisionID(rev))
+
+	return d.runInTransaction(ctx, func(ctx context.Context, tx *Tx) error {
+		revDoc, err := tx.tx.Get(revRef)
+		if err != nil {
+			return wrapError("RevertTo", collection, id, err)
+		}
+		if err := loadRevision(revDoc, obj); err != nil {
+			return fmt.Errorf("error parsing data: %v", err)
+		}
+		setDocID(obj, id)
+		return tx.save(ctx, "RevertTo", collection, id, obj)
+	})
+
+}
@@ -0,0 +1,91 @@
+package lighter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevisionID(t *testing.T) {
+	assert.Equal(t, "rev12", revisionID(12))
+	assert.True(t, IsValidID(revisionID(1)))
+}
+
+func TestRevertToArgs(t *testing.T) {
+	s := &Store{opts: makeStoreOptions([]StoreOption{WithHistory(0)})}
+	ctx := context.Background()
+	assert.NotNil(t, s.RevertTo(ctx, "col", "id1", 1, nil))
+	assert.True(t, errors.Is(s.RevertTo(ctx, "col", "1", 1, &MockedStoreObject{}), ErrInvalidID))
+}
+
+func TestHistory(t *testing.T) {
+
+	s := newTestStore(t, WithHistory(2))
+	defer s.Close()
+
+	colName := "test_history"
+	ctx := context.Background()
+
+	obj := NewTestObject("John", 1, 0.1)
+	for i := 1; i <= 3; i++ {
+		obj.Count = i
+		assert.Nil(t, s.Save(ctx, colName, obj.ID, obj))
+	}
+
+	// first save has no previous state, only the 2 latest revisions are kept
+	list, err := s.ListRevisions(ctx, colName, obj.ID)
+	assert.Nil(t, err)
+	assert.Len(t, list, 2)
+	assert.Equal(t, int64(1), list[0].Rev)
+	assert.Equal(t, "Save", list[1].Op)
+	assert.False(t, list[1].ArchivedAt.Before(list[1].UpdatedAt))
+
+	rev := &MockedStoreObject{}
+	assert.Nil(t, s.GetRevision(ctx, colName, obj.ID, 2, rev))
+	assert.Equal(t, 2, rev.Count)
+
+	err = s.GetRevision(ctx, colName, obj.ID, 7, rev)
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	assert.Nil(t, s.DeleteByID(ctx, colName, obj.ID))
+	list, err = s.ListRevisions(ctx, colName, obj.ID)
+	assert.Nil(t, err)
+	assert.Len(t, list, 2)
+	assert.Equal(t, int64(3), list[1].Rev)
+	assert.Equal(t, "DeleteByID", list[1].Op)
+
+	// reverting deleted document restores its state
+	reverted := &MockedStoreObject{}
+	assert.Nil(t, s.RevertTo(ctx, colName, obj.ID, 3, reverted))
+	assert.Equal(t, 3, reverted.Count)
+	out := &MockedStoreObject{}
+	assert.Nil(t, s.GetByID(ctx, colName, obj.ID, out))
+	assert.Equal(t, 3, out.Count)
+
+	err = s.RevertTo(ctx, colName, obj.ID, 1, reverted)
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	// revision bookkeeping fields are not restored into maps
+	m := map[string]interface{}{}
+	assert.Nil(t, s.RevertTo(ctx, colName, obj.ID, 3, m))
+	assert.Equal(t, int64(3), m["count"])
+	assert.NotContains(t, m, revisionField)
+	doc, err := s.client.Collection(colName).Doc(obj.ID).Get(ctx)
+	assert.Nil(t, err)
+	for _, f := range []string{revisionField, revisionUpdatedField, revisionArchivedField, revisionOpField} {
+		assert.NotContains(t, doc.Data(), f)
+	}
+
+	// reverted state is validated like any other save
+	v := newTestStore(t, WithHistory(2), WithValidator(colName, func(obj interface{}) error {
+		return errors.New("rejected")
+	}))
+	defer v.Close()
+	err = v.RevertTo(ctx, colName, obj.ID, 5, reverted)
+	assert.True(t, errors.Is(err, ErrValidation))
+
+	assert.Nil(t, s.DeleteAll(ctx, colName, 10, Recursive()))
+
+}
@@ -0,0 +1,86 @@
+package lighter
+
+import (
+	"context"
+)
+
+// BeforeSaver can be implemented by saved objects to normalise their fields
+// before they are validated and written. Returned error refuses the write
+type BeforeSaver interface {
+	BeforeSave(ctx context.Context) error
+}
+
+// AfterLoader can be implemented by loaded objects to compute derived
+// fields after they are loaded from the store
+type AfterLoader interface {
+	AfterLoad(ctx context.Context) error
+}
+
+// BeforeDeleter can be implemented by objects of collections registered
+// using WithBeforeDelete. Returned error refuses the delete
+type BeforeDeleter interface {
+	BeforeDelete(ctx context.Context) error
+}
+
+// WithBeforeDelete makes DeleteByID load documents of collection into object
+// created by newObj and call its BeforeDelete method before deleting them.
+// Collection is matched the same way as in WithValidator
+func WithBeforeDelete(collection string, newObj func() interface{}) StoreOption {
+	return func(o *storeOptions) {
+		if newObj == nil {
+			return
+		}
+		if o.deleteHooks == nil {
+			o.deleteHooks = map[string]func() interface{}{}
+		}
+		o.deleteHooks[collection] = newObj
+	}
+}
+
+// collectionKeys returns the keys options registered for collection are
+// looked up by: the full collection path and for nested collections its ID
+func collectionKeys(collection string) []string {
+	if id := collectionID(collection); id != collection {
+		return []string{collection, id}
+	}
+	return []string{collection}
+}
+
+// beforeSave runs BeforeSave method of obj and validates it
+func (o *storeOptions) beforeSave(ctx context.Context, op, collection, id string, obj interface{}) error {
+	if s, ok := obj.(BeforeSaver); ok {
+		if err := s.BeforeSave(ctx); err != nil {
+			return vetoError(op, collection, id, err)
+		}
+	}
+	return o.validate(op, collection, id, obj)
+}
+
+// deleteHook returns function creating object of collection
+// for BeforeDelete hook or nil when none was registered
+func (o *storeOptions) deleteHook(collection string) func() interface{} {
+	for _, k := range collectionKeys(collection) {
+		if fn, ok := o.deleteHooks[k]; ok {
+			return fn
+		}
+	}
+	return nil
+}
+
+// beforeDelete runs BeforeDelete method of obj
+func beforeDelete(ctx context.Context, op, collection, id string, obj interface{}) error {
+	if d, ok := obj.(BeforeDeleter); ok {
+		if err := d.BeforeDelete(ctx); err != nil {
+			return vetoError(op, collection, id, err)
+		}
+	}
+	return nil
+}
+
+// afterLoad runs AfterLoad method of obj
+func afterLoad(ctx context.Context, obj interface{}) error {
+	if l, ok := obj.(AfterLoader); ok {
+		return l.AfterLoad(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package lighter
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errLocked = errors.New("locked")
+
+type hookedObject struct {
+	Name   string `firestore:"name"`
+	Locked bool   `firestore:"locked"`
+	Upper  string `firestore:"-"`
+}
+
+func (o *hookedObject) BeforeSave(ctx context.Context) error {
+	if o.Name == "" {
+		return errors.New("name required")
+	}
+	o.Name = strings.TrimSpace(o.Name)
+	return nil
+}
+
+func (o *hookedObject) AfterLoad(ctx context.Context) error {
+	o.Upper = strings.ToUpper(o.Name)
+	return nil
+}
+
+func (o *hookedObject) BeforeDelete(ctx context.Context) error {
+	if o.Locked {
+		return errLocked
+	}
+	return nil
+}
+
+type hookedHandler struct {
+	items []*hookedObject
+}
+
+func (h *hookedHandler) MakeNew() interface{} {
+	return &hookedObject{}
+}
+
+func (h *hookedHandler) Append(item interface{}) {
+	h.items = append(h.items, item.(*hookedObject))
+}
+
+func newHookedObject() interface{} {
+	return &hookedObject{}
+}
+
+func TestCollectionKeys(t *testing.T) {
+	assert.Equal(t, []string{"orders"}, collectionKeys("orders"))
+	assert.Equal(t, []string{"users/u1/orders", "orders"}, collectionKeys("users/u1/orders"))
+}
+
+func TestMemoryStoreHooks(t *testing.T) {
+
+	ctx := context.Background()
+	ms := NewMemoryStore(WithBeforeDelete("hooked", newHookedObject))
+	col := "hooked"
+
+	err := ms.Save(ctx, col, "h1", &hookedObject{})
+	assert.True(t, errors.Is(err, ErrVetoed))
+
+	assert.Nil(t, ms.Save(ctx, col, "h1", &hookedObject{Name: " John "}))
+	assert.Nil(t, ms.Save(ctx, col, "h2", &hookedObject{Name: "Jane", Locked: true}))
+
+	obj := &hookedObject{}
+	assert.Nil(t, ms.GetByID(ctx, col, "h1", obj))
+	assert.Equal(t, "John", obj.Name)
+	assert.Equal(t, "JOHN", obj.Upper)
+
+	h := &hookedHandler{}
+	assert.Nil(t, ms.GetByQuery(ctx, &QueryCriteria{Collection: col}, h))
+	assert.Len(t, h.items, 2)
+	assert.Equal(t, "JANE", h.items[1].Upper)
+
+	err = ms.DeleteByID(ctx, col, "h2")
+	assert.True(t, errors.Is(err, ErrVetoed))
+	assert.True(t, errors.Is(err, errLocked))
+	assert.Nil(t, ms.GetByID(ctx, col, "h2", obj))
+
+	assert.Nil(t, ms.DeleteByID(ctx, col, "h1"))
+	assert.Nil(t, ms.DeleteByID(ctx, col, "h1"))
+
+}
+
+// parentObject refuses to be deleted while its children are stored
+type parentObject struct {
+	Name  string       `firestore:"name"`
+	store *MemoryStore `firestore:"-"`
+}
+
+func (o *parentObject) BeforeDelete(ctx context.Context) error {
+	h := &TestObjectHandler{}
+	q := &QueryCriteria{
+		Collection: "children",
+		Criteria:   []*Criterion{{Property: "name", Operator: "==", Value: o.Name}},
+	}
+	if err := o.store.GetByQuery(ctx, q, h); err != nil {
+		return err
+	}
+	if len(h.Items) > 0 {
+		return errLocked
+	}
+	return nil
+}
+
+func TestMemoryStoreHookReadsStore(t *testing.T) {
+
+	ctx := context.Background()
+	var ms *MemoryStore
+	ms = NewMemoryStore(WithBeforeDelete("parents", func() interface{} {
+		return &parentObject{store: ms}
+	}))
+
+	assert.Nil(t, ms.Save(ctx, "parents", "p1", &parentObject{Name: "p1"}))
+	child := NewTestObject("p1", 1, 0.1)
+	assert.Nil(t, ms.Save(ctx, "children", child.ID, child))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- ms.DeleteByID(ctx, "parents", "p1")
+	}()
+
+	select {
+	case err := <-done:
+		assert.True(t, errors.Is(err, errLocked))
+	case <-time.After(5 * time.Second):
+		t.Fatal("DeleteByID deadlocked in BeforeDelete hook reading the store")
+	}
+
+	assert.Nil(t, ms.DeleteByID(ctx, "children", child.ID))
+	assert.Nil(t, ms.DeleteByID(ctx, "parents", "p1"))
+	assert.NotNil(t, ms.GetByID(ctx, "parents", "p1", &parentObject{}))
+
+}
+
+func TestStoreHooks(t *testing.T) {
+
+	s := newTestStore(t, WithBeforeDelete("test_hooks", newHookedObject))
+	defer s.Close()
+
+	ctx := context.Background()
+	col := "test_hooks"
+
+	err := s.Save(ctx, col, "h1", &hookedObject{})
+	assert.True(t, errors.Is(err, ErrVetoed))
+	err = s.SaveMerge(ctx, col, "h1", &hookedObject{}, "name")
+	assert.True(t, errors.Is(err, ErrVetoed))
+
+	assert.Nil(t, s.Save(ctx, col, "h1", &hookedObject{Name: "Jane ", Locked: true}))
+
+	obj := &hookedObject{}
+	assert.Nil(t, s.GetByID(ctx, col, "h1", obj))
+	assert.Equal(t, "JANE", obj.Upper)
+
+	h := &hookedHandler{}
+	assert.Nil(t, s.GetByQuery(ctx, &QueryCriteria{Collection: col}, h))
+	assert.Len(t, h.items, 1)
+	assert.Equal(t, "JANE", h.items[0].Upper)
+
+	err = s.DeleteByID(ctx, col, "h1")
+	assert.True(t, errors.Is(err, errLocked))
+
+	assert.Nil(t, store.DeleteByID(ctx, col, "h1"))
+
+}
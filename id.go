@@ -3,6 +3,7 @@ package lighter
 import (
 	"fmt"
 	"hash/fnv"
+	"strings"
 
 	uuid "github.com/satori/go.uuid"
 )
@@ -23,10 +24,12 @@ func ToID(val string) string {
 	return fmt.Sprintf("%s%d", idPrefix, h.Sum32())
 }
 
-// IsValidID validates that passed value is a valid Firestore ID
+// IsValidID validates that passed value is a valid Firestore ID. IDs must start
+// with letter (which also excludes "." and "..") and must not contain "/",
+// otherwise the ID would address document in a nested collection
 func IsValidID(val string) bool {
 
-	if val == "" {
+	if val == "" || strings.Contains(val, pathSeparator) {
 		return false
 	}
 
@@ -21,4 +21,9 @@ func TestToID(t *testing.T) {
 func TestIsValidID(t *testing.T) {
 	assert.False(t, IsValidID("1234567"))
 	assert.True(t, IsValidID("a1234567"))
+	assert.False(t, IsValidID(""))
+	assert.False(t, IsValidID("."))
+	assert.False(t, IsValidID(".."))
+	assert.False(t, IsValidID("u1/orders/o1"))
+	assert.False(t, IsValidID("u1/"))
 }
@@ -0,0 +1,76 @@
+package lighter
+
+import (
+	"context"
+)
+
+// Op describes store operation passed to interceptors
+type Op struct {
+	// Name is the name of the store method (e.g. Save or GetByQuery)
+	Name string
+	// Collection is the collection (or collection group) the operation works on
+	Collection string
+	// ID is the document ID, empty for operations on multiple documents
+	ID string
+	// Query is the query criteria of query operations
+	Query *QueryCriteria
+
+	// retry tells whether the operation is retried as a whole by the retry policy
+	retry retryMode
+}
+
+// Interceptor wraps store operation op. It must call next to execute the
+// operation (or the next interceptor), possibly with modified context,
+// and return its error unless it decides to fail the operation itself
+type Interceptor func(ctx context.Context, op Op, next func(ctx context.Context) error) error
+
+// WithInterceptors adds interceptors wrapping every store operation.
+// Interceptors are run in the order they were added, first one outermost
+func WithInterceptors(interceptors ...Interceptor) StoreOption {
+	return func(o *storeOptions) {
+		for _, i := range interceptors {
+			if i != nil {
+				o.interceptors = append(o.interceptors, i)
+			}
+		}
+	}
+}
+
+// opKey is the context key of the running store operation
+type opKey struct{}
+
+// intercept runs fn wrapped in the interceptors of store, retries it
+// according to the retry policy and records its trace span and metrics.
+// Each retry runs the interceptors again
+func (o *storeOptions) intercept(ctx context.Context, op Op, fn func(ctx context.Context) error) error {
+	ctx = context.WithValue(ctx, opKey{}, op)
+	return observe(ctx, op, func(ctx context.Context) error {
+		return o.retryOp(ctx, op, func(ctx context.Context) error {
+			return runInterceptors(ctx, o.interceptors, op, fn)
+		})
+	})
+}
+
+// opFromContext returns the store operation running in ctx
+func opFromContext(ctx context.Context) Op {
+	op, _ := ctx.Value(opKey{}).(Op)
+	return op
+}
+
+func runInterceptors(ctx context.Context, list []Interceptor, op Op, fn func(ctx context.Context) error) error {
+	if len(list) == 0 {
+		return fn(ctx)
+	}
+	return list[0](ctx, op, func(ctx context.Context) error {
+		return runInterceptors(ctx, list[1:], op, fn)
+	})
+}
+
+// queryOp describes query operation name on q
+func queryOp(name string, q *QueryCriteria) Op {
+	op := Op{Name: name, Query: q}
+	if q != nil {
+		op.Collection = q.Collection
+	}
+	return op
+}
@@ -0,0 +1,113 @@
+package lighter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ctxKey string
+
+func TestInterceptorOrder(t *testing.T) {
+
+	calls := make([]string, 0)
+	trace := func(name string) Interceptor {
+		return func(ctx context.Context, op Op, next func(ctx context.Context) error) error {
+			calls = append(calls, name+" "+op.Name)
+			return next(context.WithValue(ctx, ctxKey(name), true))
+		}
+	}
+
+	o := makeStoreOptions([]StoreOption{WithInterceptors(trace("a"), nil, trace("b"))})
+	err := o.intercept(context.Background(), Op{Name: "Save"}, func(ctx context.Context) error {
+		assert.Equal(t, true, ctx.Value(ctxKey("a")))
+		assert.Equal(t, true, ctx.Value(ctxKey("b")))
+		calls = append(calls, "op")
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a Save", "b Save", "op"}, calls)
+
+	// operation runs directly without interceptors
+	err = makeStoreOptions(nil).intercept(context.Background(), Op{}, func(ctx context.Context) error {
+		return errNameRequired
+	})
+	assert.Equal(t, errNameRequired, err)
+
+}
+
+func TestMemoryStoreInterceptors(t *testing.T) {
+
+	errDenied := errors.New("denied")
+	ops := make([]Op, 0)
+	ms := NewMemoryStore(WithInterceptors(func(ctx context.Context, op Op, next func(ctx context.Context) error) error {
+		ops = append(ops, op)
+		if op.Collection == "secret" {
+			return errDenied
+		}
+		return next(ctx)
+	}))
+
+	ctx := context.Background()
+	obj := NewTestObject("John", 1, 0.1)
+	q := &QueryCriteria{Collection: "public"}
+
+	assert.Nil(t, ms.Save(ctx, "public", obj.ID, obj))
+	assert.Nil(t, ms.GetByID(ctx, "public", obj.ID, &MockedStoreObject{}))
+	assert.Nil(t, ms.GetByQuery(ctx, q, &TestObjectHandler{}))
+	assert.Nil(t, ms.DeleteByID(ctx, "public", obj.ID))
+	assert.Nil(t, ms.DeleteAll(ctx, "public", 10))
+	assert.Equal(t, errDenied, ms.Save(ctx, "secret", obj.ID, obj))
+
+	assert.Equal(t, []Op{
+		{Name: "Save", Collection: "public", ID: obj.ID, retry: retryIdempotent},
+		{Name: "GetByID", Collection: "public", ID: obj.ID, retry: retryIdempotent},
+		{Name: "GetByQuery", Collection: "public", Query: q},
+		{Name: "DeleteByID", Collection: "public", ID: obj.ID, retry: retryIdempotent},
+		{Name: "DeleteAll", Collection: "public"},
+		{Name: "Save", Collection: "secret", ID: obj.ID, retry: retryIdempotent},
+	}, ops)
+
+}
+
+func TestStoreInterceptors(t *testing.T) {
+
+	errDenied := errors.New("denied")
+	ops := make([]Op, 0)
+	s := &Store{opts: makeStoreOptions([]StoreOption{WithInterceptors(
+		func(ctx context.Context, op Op, next func(ctx context.Context) error) error {
+			ops = append(ops, op)
+			return errDenied
+		},
+	)})}
+
+	ctx := context.Background()
+	obj := NewTestObject("John", 1, 0.1)
+
+	// interceptors can fail operations before they reach Firestore
+	assert.Equal(t, errDenied, s.Save(ctx, "c", obj.ID, obj))
+	assert.Equal(t, errDenied, s.Increment(ctx, "c", obj.ID, "count", 1))
+	assert.Equal(t, errDenied, s.Restore(ctx, "c", obj.ID))
+
+	id, err := s.Add(ctx, "c", obj)
+	assert.Equal(t, errDenied, err)
+	assert.Equal(t, "", id)
+
+	_, err = s.DeleteByQuery(ctx, nil, 10)
+	assert.Equal(t, errDenied, err)
+
+	assert.Equal(t, []string{"Save", "Increment", "Restore", "Add", "DeleteByQuery"}, opNames(ops))
+	assert.NotEqual(t, "", ops[3].ID)
+	assert.Equal(t, "", ops[4].Collection)
+
+}
+
+func opNames(ops []Op) []string {
+	names := make([]string, 0, len(ops))
+	for _, op := range ops {
+		names = append(names, op.Name)
+	}
+	return names
+}
@@ -0,0 +1,148 @@
+// Package emulator starts, attaches to and resets local Firestore emulator
+package emulator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+const (
+	// HostEnvVar is the variable used by Firestore clients to locate the emulator
+	HostEnvVar = "FIRESTORE_EMULATOR_HOST"
+	// RequireEnvVar makes tests which need the emulator fail instead of
+	// being skipped when it is not available (e.g. in CI)
+	RequireEnvVar = "LIGHTER_REQUIRE_EMULATOR"
+
+	startTimeout = 60 * time.Second
+)
+
+// ErrNotAvailable is returned when there is no emulator to attach to
+// and gcloud is not installed to start one
+var ErrNotAvailable = errors.New("firestore emulator not available")
+
+// Required reports whether RequireEnvVar is set to true
+func Required() bool {
+	required, _ := strconv.ParseBool(os.Getenv(RequireEnvVar))
+	return required
+}
+
+// Emulator represents running Firestore emulator
+type Emulator struct {
+	// Host is the emulator host and port (e.g. localhost:8080)
+	Host string
+
+	cmd *exec.Cmd
+}
+
+// Start attaches to the emulator defined in FIRESTORE_EMULATOR_HOST
+// or starts new one using gcloud on a free local port
+func Start(ctx context.Context) (*Emulator, error) {
+
+	if host := os.Getenv(HostEnvVar); host != "" {
+		return &Emulator{Host: host}, nil
+	}
+
+	gcloud, err := exec.LookPath("gcloud")
+	if err != nil {
+		return nil, ErrNotAvailable
+	}
+
+	host, err := freeHost()
+	if err != nil {
+		return nil, fmt.Errorf("error finding free port: %v", err)
+	}
+
+	cmd := exec.Command(gcloud, "beta", "emulators", "firestore", "start",
+		"--quiet", fmt.Sprintf("--host-port=%s", host))
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("error starting emulator: %v", err)
+	}
+
+	e := &Emulator{Host: host, cmd: cmd}
+	if err := e.wait(ctx); err != nil {
+		e.Stop()
+		return nil, err
+	}
+
+	return e, nil
+
+}
+
+// Stop terminates the emulator if it was started by Start
+func (e *Emulator) Stop() error {
+	if e.cmd == nil || e.cmd.Process == nil {
+		return nil
+	}
+	if err := killProcessGroup(e.cmd); err != nil {
+		return err
+	}
+	e.cmd.Wait()
+	e.cmd = nil
+	return nil
+}
+
+// Reset deletes all documents of the project database in the emulator
+func (e *Emulator) Reset(ctx context.Context, projectID string) error {
+
+	url := fmt.Sprintf("http://%s/emulator/v1/projects/%s/databases/(default)/documents",
+		e.Host, projectID)
+	req, err := http.NewRequest(http.MethodDelete, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("error resetting emulator: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error resetting emulator: %s", resp.Status)
+	}
+
+	return nil
+
+}
+
+// wait blocks until emulator responds on its host
+func (e *Emulator) wait(ctx context.Context) error {
+
+	ctx, cancel := context.WithTimeout(ctx, startTimeout)
+	defer cancel()
+
+	for {
+		req, err := http.NewRequest(http.MethodGet, "http://"+e.Host, nil)
+		if err != nil {
+			return err
+		}
+		if resp, err := http.DefaultClient.Do(req.WithContext(ctx)); err == nil {
+			resp.Body.Close()
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("emulator did not start on %s: %v", e.Host, ctx.Err())
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+
+}
+
+func freeHost() (string, error) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return "", err
+	}
+	defer l.Close()
+	return fmt.Sprintf("localhost:%d", l.Addr().(*net.TCPAddr).Port), nil
+}
@@ -0,0 +1,66 @@
+package emulator
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttachAndReset(t *testing.T) {
+
+	var method, path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+	}))
+	defer srv.Close()
+
+	host := strings.TrimPrefix(srv.URL, "http://")
+	prev, ok := os.LookupEnv(HostEnvVar)
+	os.Setenv(HostEnvVar, host)
+	defer func() {
+		if ok {
+			os.Setenv(HostEnvVar, prev)
+			return
+		}
+		os.Unsetenv(HostEnvVar)
+	}()
+
+	ctx := context.Background()
+	e, err := Start(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, host, e.Host)
+
+	err = e.Reset(ctx, "test")
+	assert.Nil(t, err)
+	assert.Equal(t, http.MethodDelete, method)
+	assert.Equal(t, "/emulator/v1/projects/test/databases/(default)/documents", path)
+
+	// attached emulator is not owned so stop is a no-op
+	assert.Nil(t, e.Stop())
+
+}
+
+func TestRequired(t *testing.T) {
+
+	prev, ok := os.LookupEnv(RequireEnvVar)
+	defer func() {
+		if ok {
+			os.Setenv(RequireEnvVar, prev)
+			return
+		}
+		os.Unsetenv(RequireEnvVar)
+	}()
+
+	os.Setenv(RequireEnvVar, "true")
+	assert.True(t, Required())
+	os.Setenv(RequireEnvVar, "0")
+	assert.False(t, Required())
+	os.Unsetenv(RequireEnvVar)
+	assert.False(t, Required())
+
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+// +build !windows
+
+package emulator
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// gcloud runs the emulator as a child java process so the whole group
+// needs to be terminated on stop
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+func killProcessGroup(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+}
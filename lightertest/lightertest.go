@@ -0,0 +1,160 @@
+// Package lightertest provides Firestore emulator backed test harness for lighter
+//
+// Typical use starts (or attaches to) the emulator once in TestMain and
+// creates new isolated environment in each test:
+//
+//	var emu *lightertest.Emulator
+//
+//	func TestMain(m *testing.M) {
+//		emu, _ = lightertest.Start(context.Background())
+//		code := m.Run()
+//		emu.Stop()
+//		os.Exit(code)
+//	}
+//
+//	func TestSomething(t *testing.T) {
+//		env := emu.NewEnv(t) // skips test when emulator is not available (see RequireEnvVar)
+//		defer env.Close()
+//		err := env.Store.Save(ctx, env.Collection("products"), id, p)
+//	}
+package lightertest
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mchmarny/lighter"
+	"github.com/mchmarny/lighter/internal/emulator"
+)
+
+// HostEnvVar is the variable used to attach to an already running emulator
+const HostEnvVar = emulator.HostEnvVar
+
+// RequireEnvVar set to true makes NewEnv fail the test instead of skipping it
+// when emulator is not available, so that CI does not silently skip tests
+const RequireEnvVar = emulator.RequireEnvVar
+
+// ErrNotAvailable is returned by Start when there is no emulator to attach to
+// and gcloud is not installed to start one
+var ErrNotAvailable = emulator.ErrNotAvailable
+
+var (
+	envCounter uint64
+	nameChars  = regexp.MustCompile("[^a-zA-Z0-9]+")
+)
+
+// Emulator represents Firestore emulator used by tests
+type Emulator struct {
+	emu *emulator.Emulator
+}
+
+// Start attaches to the emulator defined in FIRESTORE_EMULATOR_HOST
+// or starts new one using gcloud on a free local port
+func Start(ctx context.Context) (*Emulator, error) {
+	emu, err := emulator.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Emulator{emu: emu}, nil
+}
+
+// Host returns the emulator host and port
+func (e *Emulator) Host() string {
+	if e == nil {
+		return ""
+	}
+	return e.emu.Host
+}
+
+// Stop terminates the emulator if it was started by Start
+func (e *Emulator) Stop() error {
+	if e == nil {
+		return nil
+	}
+	return e.emu.Stop()
+}
+
+// Reset deletes all documents of the project in the emulator
+func (e *Emulator) Reset(ctx context.Context, projectID string) error {
+	if e == nil {
+		return ErrNotAvailable
+	}
+	return e.emu.Reset(ctx, projectID)
+}
+
+// NewStore creates lighter store connected to the emulator
+func (e *Emulator) NewStore(ctx context.Context, opts ...lighter.StoreOption) (*lighter.Store, error) {
+	if e == nil {
+		return nil, ErrNotAvailable
+	}
+	return lighter.NewEmulatorStore(ctx, e.emu.Host, opts...)
+}
+
+// Env is an isolated test environment with its own emulator project
+// and collection prefix
+type Env struct {
+	// Store is connected to the emulator project of this environment
+	Store *lighter.Store
+	// ProjectID is the emulator project unique to this environment
+	ProjectID string
+	// Prefix is prepended to collection names by Collection
+	Prefix string
+
+	t   testing.TB
+	emu *Emulator
+}
+
+// NewEnv creates new isolated environment for test t. When emulator is nil
+// (not available) the test is skipped, or failed when RequireEnvVar is set
+func (e *Emulator) NewEnv(t testing.TB) *Env {
+	t.Helper()
+
+	if e == nil {
+		if emulator.Required() {
+			t.Fatal(ErrNotAvailable.Error())
+		}
+		t.Skip(ErrNotAvailable.Error())
+	}
+
+	n := atomic.AddUint64(&envCounter, 1)
+	env := &Env{
+		ProjectID: fmt.Sprintf("lighter-test-%d", n),
+		Prefix:    collectionPrefix(t.Name(), n),
+		t:         t,
+		emu:       e,
+	}
+
+	s, err := e.NewStore(context.Background(), lighter.WithProjectID(env.ProjectID))
+	if err != nil {
+		t.Fatalf("error creating emulator store: %v", err)
+	}
+	env.Store = s
+
+	return env
+}
+
+// Collection returns collection name unique to this environment
+func (env *Env) Collection(name string) string {
+	return env.Prefix + name
+}
+
+// Close wipes all data written in this environment and closes its store
+func (env *Env) Close() {
+	env.t.Helper()
+
+	if err := env.emu.Reset(context.Background(), env.ProjectID); err != nil {
+		env.t.Errorf("error wiping emulator data: %v", err)
+	}
+
+	if err := env.Store.Close(); err != nil {
+		env.t.Errorf("error closing store: %v", err)
+	}
+}
+
+// collectionPrefix derives valid collection name prefix from test name
+func collectionPrefix(testName string, n uint64) string {
+	return fmt.Sprintf("%s_%d_", nameChars.ReplaceAllString(testName, "_"), n)
+}
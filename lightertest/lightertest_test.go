@@ -0,0 +1,59 @@
+package lightertest
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/mchmarny/lighter"
+	"github.com/stretchr/testify/assert"
+)
+
+var emu *Emulator
+
+func TestMain(m *testing.M) {
+	emu, _ = Start(context.Background())
+	code := m.Run()
+	emu.Stop()
+	os.Exit(code)
+}
+
+func TestCollectionPrefix(t *testing.T) {
+	assert.Equal(t, "TestA_b_c_2_", collectionPrefix("TestA/b c", 2))
+}
+
+func TestNilEmulator(t *testing.T) {
+	var e *Emulator
+	assert.Equal(t, "", e.Host())
+	assert.Nil(t, e.Stop())
+	assert.Equal(t, ErrNotAvailable, e.Reset(context.Background(), "test"))
+
+	s, err := e.NewStore(context.Background())
+	assert.Equal(t, ErrNotAvailable, err)
+	assert.Nil(t, s)
+}
+
+func TestEnv(t *testing.T) {
+
+	env := emu.NewEnv(t)
+	defer env.Close()
+
+	ctx := context.Background()
+	col := env.Collection("products")
+	obj := lighter.NewTestObject("John", 40, 2.75)
+
+	err := env.Store.Save(ctx, col, obj.ID, obj)
+	assert.Nil(t, err)
+
+	obj2 := &lighter.MockedStoreObject{}
+	err = env.Store.GetByID(ctx, col, obj.ID, obj2)
+	assert.Nil(t, err)
+	assert.Equal(t, obj.ID, obj2.ID)
+
+	err = emu.Reset(ctx, env.ProjectID)
+	assert.Nil(t, err)
+
+	err = env.Store.GetByID(ctx, col, obj.ID, obj2)
+	assert.NotNil(t, err)
+
+}
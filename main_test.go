@@ -2,19 +2,64 @@ package lighter
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"testing"
+
+	"github.com/mchmarny/lighter/internal/emulator"
 )
 
 var store *Store
 
 func TestMain(m *testing.M) {
+	os.Exit(runTests(m))
+}
+
+// runTests runs all tests against Firestore emulator, tests which
+// require Firestore are skipped when emulator is not available
+// unless it is required (see emulator.RequireEnvVar)
+func runTests(m *testing.M) int {
 	ctx := context.Background()
-	s, err := NewStore(ctx)
+
+	emu, err := emulator.Start(ctx)
+	if err != nil {
+		if emulator.Required() {
+			fmt.Printf("error starting required Firestore emulator: %v\n", err)
+			return 1
+		}
+		fmt.Printf("skipping Firestore tests: %v\n", err)
+		return m.Run()
+	}
+	defer emu.Stop()
+
+	// clients created in tests without explicit host use the same emulator
+	os.Setenv(emulator.HostEnvVar, emu.Host)
+
+	s, err := NewEmulatorStore(ctx, emu.Host)
 	if err != nil {
 		panic(err)
 	}
 	store = s
 	defer store.Close()
-	os.Exit(m.Run())
+	defer emu.Reset(ctx, emulatorProjectID)
+
+	return m.Run()
+}
+
+// requireStore skips the test when Firestore emulator is not available
+func requireStore(t *testing.T) {
+	if store == nil {
+		t.Skip("Firestore emulator not available")
+	}
+}
+
+// newTestStore creates new emulator store with options, skips the test
+// when Firestore emulator is not available
+func newTestStore(t *testing.T, opts ...StoreOption) *Store {
+	requireStore(t)
+	s, err := NewEmulatorStore(context.Background(), os.Getenv(emulator.HostEnvVar), opts...)
+	if err != nil {
+		t.Fatalf("error creating store: %v", err)
+	}
+	return s
 }
@@ -0,0 +1,513 @@
+package lighter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore is an in-memory implementation of DocumentStore
+// intended for unit tests which should not depend on live Firestore
+type MemoryStore struct {
+	mu          sync.RWMutex
+	collections map[string]map[string]map[string]interface{}
+	opts        *storeOptions
+}
+
+// NewMemoryStore creates new empty in-memory store. Options which configure
+// the Firestore client are ignored
+func NewMemoryStore(opts ...StoreOption) *MemoryStore {
+	return &MemoryStore{
+		collections: map[string]map[string]map[string]interface{}{},
+		opts:        makeStoreOptions(opts),
+	}
+}
+
+// Save inserts or updates by ID
+func (d *MemoryStore) Save(ctx context.Context, collection string, id string, obj interface{}) error {
+	return d.opts.intercept(ctx, Op{Name: "Save", Collection: collection, ID: id, retry: retryIdempotent}, func(ctx context.Context) error {
+		return recordWrite(ctx, d.save(ctx, collection, id, obj))
+	})
+}
+
+func (d *MemoryStore) save(ctx context.Context, collection string, id string, obj interface{}) error {
+
+	if obj == nil {
+		return errors.New("object required")
+	}
+
+	if err := validateDocArgs(collection, id); err != nil {
+		return err
+	}
+
+	if err := d.opts.beforeSave(ctx, "Save", collection, id, obj); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var created time.Time
+	if field := createdAtField(obj); field != "" {
+		created, _ = d.collections[collection][id][field].(time.Time)
+	}
+
+	now := d.opts.clock()
+	doc, err := toMemoryDoc(stampTimes(obj, now, created, false), now)
+	if err != nil {
+		return err
+	}
+
+	col, ok := d.collections[collection]
+	if !ok {
+		col = map[string]map[string]interface{}{}
+		d.collections[collection] = col
+	}
+	col[id] = doc
+
+	return nil
+
+}
+
+// GetByID returns stored object for given ID
+func (d *MemoryStore) GetByID(ctx context.Context, collection, id string, in interface{}) error {
+	return d.opts.intercept(ctx, Op{Name: "GetByID", Collection: collection, ID: id, retry: retryIdempotent}, func(ctx context.Context) error {
+		return d.getByID(ctx, collection, id, in)
+	})
+}
+
+func (d *MemoryStore) getByID(ctx context.Context, collection, id string, in interface{}) error {
+
+	if err := validateDocArgs(collection, id); err != nil {
+		return err
+	}
+
+	d.mu.RLock()
+	doc, ok := d.collections[collection][id]
+	d.mu.RUnlock()
+
+	if !ok || d.isHidden(doc, false) {
+		return notFoundError("GetByID", collection, id)
+	}
+
+	if err := fromMemoryDoc(doc, in); err != nil {
+		return fmt.Errorf("error parsing data: %v", err)
+	}
+
+	if err := applyMetadata(in, &Metadata{ID: id, Path: Path(collection, id)}); err != nil {
+		return fmt.Errorf("error setting metadata: %v", err)
+	}
+
+	if err := afterLoad(ctx, in); err != nil {
+		return wrapError("GetByID", collection, id, err)
+	}
+
+	recordRead(ctx, 1)
+	return nil
+
+}
+
+// GetByQuery allows for filtered query using QueryHandler
+func (d *MemoryStore) GetByQuery(ctx context.Context, q *QueryCriteria, h ResultHandler) error {
+	return d.opts.intercept(ctx, queryOp("GetByQuery", q), func(ctx context.Context) error {
+		return d.getByQuery(ctx, q, h)
+	})
+}
+
+func (d *MemoryStore) getByQuery(ctx context.Context, q *QueryCriteria, h ResultHandler) error {
+
+	if q == nil {
+		return fmt.Errorf("query required")
+	}
+
+	if h == nil {
+		return fmt.Errorf("handler required")
+	}
+
+	docs, err := d.query(q)
+	if err != nil {
+		return fmt.Errorf("error building query: %w", err)
+	}
+
+	for _, doc := range docs {
+		item := h.MakeNew()
+		if err := fromMemoryDoc(doc.data, &item); err != nil {
+			return err
+		}
+		if err := applyMetadata(item, &Metadata{ID: collectionID(doc.path), Path: doc.path}); err != nil {
+			return err
+		}
+		if err := afterLoad(ctx, item); err != nil {
+			return err
+		}
+		appendItem(h, item, doc.path)
+	}
+
+	recordResults(ctx, len(docs))
+	return nil
+
+}
+
+// DeleteByID deletes stored object for a given ID. When store uses soft delete
+// the document is only marked as deleted (see WithSoftDelete)
+func (d *MemoryStore) DeleteByID(ctx context.Context, collection, id string) error {
+	return d.opts.intercept(ctx, Op{Name: "DeleteByID", Collection: collection, ID: id, retry: retryIdempotent}, func(ctx context.Context) error {
+		return recordWrite(ctx, d.deleteByID(ctx, collection, id))
+	})
+}
+
+func (d *MemoryStore) deleteByID(ctx context.Context, collection, id string) error {
+
+	if err := validateDocArgs(collection, id); err != nil {
+		return err
+	}
+
+	// stored documents are replaced rather than modified so the document
+	// can be passed to the hook without holding the lock, which lets
+	// the hook read the store
+	d.mu.RLock()
+	doc, ok := d.collections[collection][id]
+	d.mu.RUnlock()
+	if !ok || d.isHidden(doc, false) {
+		return nil
+	}
+
+	if newObj := d.opts.deleteHook(collection); newObj != nil {
+		obj := newObj()
+		if err := fromMemoryDoc(doc, obj); err != nil {
+			return fmt.Errorf("error parsing data: %v", err)
+		}
+		if err := applyMetadata(obj, &Metadata{ID: id, Path: Path(collection, id)}); err != nil {
+			return fmt.Errorf("error setting metadata: %v", err)
+		}
+		if err := beforeDelete(ctx, "DeleteByID", collection, id, obj); err != nil {
+			return err
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// the document may have been deleted while the hook was running
+	doc, ok = d.collections[collection][id]
+	if !ok {
+		return nil
+	}
+
+	if d.opts.softDelete {
+		marked := make(map[string]interface{}, len(doc)+1)
+		for k, v := range doc {
+			marked[k] = v
+		}
+		marked[DeletedAtField] = d.opts.clock().UTC().Truncate(time.Microsecond)
+		d.collections[collection][id] = marked
+		return nil
+	}
+
+	delete(d.collections[collection], id)
+	return nil
+
+}
+
+// DeleteAll deletes all items in a collection, with Recursive option
+// also the collections nested under its documents
+func (d *MemoryStore) DeleteAll(ctx context.Context, collection string, batchSize int, opts ...DeleteOption) error {
+	return d.opts.intercept(ctx, Op{Name: "DeleteAll", Collection: collection}, func(ctx context.Context) error {
+		return d.deleteAll(ctx, collection, batchSize, opts...)
+	})
+}
+
+func (d *MemoryStore) deleteAll(ctx context.Context, collection string, batchSize int, opts ...DeleteOption) error {
+
+	if err := validateCollection(collection); err != nil {
+		return err
+	}
+
+	o := makeDeleteOptions(opts)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	names := []string{collection}
+	if o.recursive {
+		for name := range d.collections {
+			if strings.HasPrefix(name, collection+"/") {
+				names = append(names, name)
+			}
+		}
+	}
+
+	count := 0
+	for _, name := range names {
+		count += len(d.collections[name])
+		if !o.dryRun {
+			delete(d.collections, name)
+		}
+	}
+	if !o.dryRun {
+		recordWritten(ctx, count)
+	}
+	o.report(count)
+
+	return nil
+
+}
+
+// Restore removes the deleted mark from document deleted by store using soft delete.
+// Fails with ErrNotFound when the document does not exist
+func (d *MemoryStore) Restore(ctx context.Context, collection, id string) error {
+	return d.opts.intercept(ctx, Op{Name: "Restore", Collection: collection, ID: id, retry: retryIdempotent}, func(ctx context.Context) error {
+
+		if err := validateDocArgs(collection, id); err != nil {
+			return err
+		}
+
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		doc, ok := d.collections[collection][id]
+		if !ok {
+			return notFoundError("Restore", collection, id)
+		}
+
+		restored := make(map[string]interface{}, len(doc))
+		for k, v := range doc {
+			if k != DeletedAtField {
+				restored[k] = v
+			}
+		}
+		d.collections[collection][id] = restored
+
+		recordWritten(ctx, 1)
+		return nil
+
+	})
+}
+
+// PurgeDeleted permanently deletes documents marked as deleted more than
+// olderThan ago and returns the number of purged documents
+func (d *MemoryStore) PurgeDeleted(ctx context.Context, collection string, olderThan time.Duration) (purged int, err error) {
+	err = d.opts.intercept(ctx, Op{Name: "PurgeDeleted", Collection: collection}, func(ctx context.Context) error {
+
+		if err := validateCollection(collection); err != nil {
+			return err
+		}
+
+		before := d.opts.clock().Add(-olderThan)
+
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		for id, doc := range d.collections[collection] {
+			if t, ok := doc[DeletedAtField].(time.Time); ok && t.Before(before) {
+				delete(d.collections[collection], id)
+				purged++
+			}
+		}
+
+		recordWritten(ctx, purged)
+		return nil
+
+	})
+	return purged, err
+}
+
+// isHidden reports whether doc is marked as deleted and should be excluded
+// from results of store using soft delete
+func (d *MemoryStore) isHidden(doc map[string]interface{}, includeDeleted bool) bool {
+	if includeDeleted || !d.opts.softDelete {
+		return false
+	}
+	v, ok := doc[DeletedAtField]
+	return ok && v != nil
+}
+
+// Close is a no-op for the in-memory store
+func (d *MemoryStore) Close() error {
+	return nil
+}
+
+type memoryDoc struct {
+	path string
+	data map[string]interface{}
+}
+
+// query returns documents matching criteria in the Firestore result order
+func (d *MemoryStore) query(q *QueryCriteria) ([]*memoryDoc, error) {
+
+	if err := validateQueryCollection(q); err != nil {
+		return nil, err
+	}
+
+	filters := make([]*memoryFilter, 0)
+	for _, c := range q.Criteria {
+		f, err := newMemoryFilter(c, d.opts.clock())
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+
+	d.mu.RLock()
+	docs := make([]*memoryDoc, 0)
+	for name, col := range d.collections {
+		if name != q.Collection && !(q.CollectionGroup && strings.HasSuffix(name, pathSeparator+q.Collection)) {
+			continue
+		}
+		for id, data := range col {
+			if !d.isHidden(data, q.IncludeDeleted) && matchesMemoryFilters(data, filters) {
+				docs = append(docs, &memoryDoc{path: Path(name, id), data: data})
+			}
+		}
+	}
+	d.mu.RUnlock()
+
+	if q.OrderBy != nil {
+		// documents without the order by property are not returned by Firestore
+		ordered := docs[:0]
+		for _, doc := range docs {
+			if _, ok := memoryValueAt(doc.data, q.OrderBy.Property); ok {
+				ordered = append(ordered, doc)
+			}
+		}
+		docs = ordered
+	}
+
+	sort.SliceStable(docs, func(i, j int) bool {
+		if q.OrderBy != nil {
+			a, _ := memoryValueAt(docs[i].data, q.OrderBy.Property)
+			b, _ := memoryValueAt(docs[j].data, q.OrderBy.Property)
+			if c := compareMemoryValues(a, b); c != 0 {
+				if q.OrderBy.Descending {
+					return c > 0
+				}
+				return c < 0
+			}
+		}
+		return docs[i].path < docs[j].path
+	})
+
+	return docs, nil
+
+}
+
+// memoryFilter is a single where clause evaluated against stored documents
+type memoryFilter struct {
+	path  string
+	op    string
+	value interface{}
+}
+
+func newMemoryFilter(c *Criterion, now time.Time) (*memoryFilter, error) {
+
+	if c == nil {
+		return nil, errors.New("nil criterion")
+	}
+
+	val, err := toMemoryValue(reflect.ValueOf(c.Value), now)
+	if err != nil {
+		return nil, err
+	}
+
+	switch c.Operator {
+	case "==", "<", "<=", ">", ">=", "array-contains":
+	case "in", "array-contains-any":
+		if _, ok := val.([]interface{}); !ok {
+			return nil, fmt.Errorf("operator %q requires slice value", c.Operator)
+		}
+	default:
+		return nil, fmt.Errorf("invalid operator %q", c.Operator)
+	}
+
+	return &memoryFilter{
+		path:  c.Property,
+		op:    c.Operator,
+		value: val,
+	}, nil
+
+}
+
+func matchesMemoryFilters(doc map[string]interface{}, filters []*memoryFilter) bool {
+	for _, f := range filters {
+		if !f.matches(doc) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *memoryFilter) matches(doc map[string]interface{}) bool {
+
+	val, ok := memoryValueAt(doc, f.path)
+	if !ok {
+		return false
+	}
+
+	switch f.op {
+	case "==":
+		return memoryValuesEqual(val, f.value)
+	case "<", "<=", ">", ">=":
+		if memoryTypeOrder(val) != memoryTypeOrder(f.value) || isNaN(val) || isNaN(f.value) {
+			return false
+		}
+		c := compareMemoryValues(val, f.value)
+		switch f.op {
+		case "<":
+			return c < 0
+		case "<=":
+			return c <= 0
+		case ">":
+			return c > 0
+		}
+		return c >= 0
+	case "in":
+		for _, item := range f.value.([]interface{}) {
+			if memoryValuesEqual(val, item) {
+				return true
+			}
+		}
+	case "array-contains":
+		list, ok := val.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, item := range list {
+			if memoryValuesEqual(item, f.value) {
+				return true
+			}
+		}
+	case "array-contains-any":
+		list, ok := val.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, item := range list {
+			for _, want := range f.value.([]interface{}) {
+				if memoryValuesEqual(item, want) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+
+}
+
+func memoryValuesEqual(a, b interface{}) bool {
+	if isNaN(a) || isNaN(b) {
+		return isNaN(a) && isNaN(b)
+	}
+	return memoryTypeOrder(a) == memoryTypeOrder(b) && compareMemoryValues(a, b) == 0
+}
+
+func isNaN(v interface{}) bool {
+	f, ok := v.(float64)
+	return ok && math.IsNaN(f)
+}
@@ -0,0 +1,251 @@
+package lighter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStoreGetByID(t *testing.T) {
+
+	colName := "test_memory_get"
+	ctx := context.Background()
+	ms := NewMemoryStore()
+
+	obj := NewTestObject("John", 40, 2.75)
+	err := ms.Save(ctx, colName, obj.ID, obj)
+	assert.Nil(t, err)
+
+	obj2 := &MockedStoreObject{}
+	err = ms.GetByID(ctx, colName, obj.ID, obj2)
+	assert.Nil(t, err)
+	assert.Equal(t, obj.ID, obj2.ID)
+	assert.Equal(t, obj.Name, obj2.Name)
+	assert.Equal(t, obj.Count, obj2.Count)
+	assert.Equal(t, obj.Value, obj2.Value)
+	assert.True(t, obj.On.Truncate(time.Microsecond).Equal(obj2.On))
+
+	err = ms.DeleteByID(ctx, colName, obj.ID)
+	assert.Nil(t, err)
+
+	err = ms.GetByID(ctx, colName, obj.ID, obj2)
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+}
+
+func TestMemoryStoreValidation(t *testing.T) {
+
+	ctx := context.Background()
+	ms := NewMemoryStore()
+	obj := NewTestObject("John", 40, 2.75)
+
+	assert.True(t, errors.Is(ms.Save(ctx, "test_memory_valid", "1234", obj), ErrInvalidID))
+	assert.True(t, errors.Is(ms.Save(ctx, "users", "u1/orders/o1", obj), ErrInvalidID))
+	assert.True(t, errors.Is(ms.Save(ctx, "", obj.ID, obj), ErrCollectionRequired))
+	assert.NotNil(t, ms.Save(ctx, "test_memory_valid", obj.ID, nil))
+	assert.NotNil(t, ms.GetByID(ctx, "", obj.ID, obj))
+	assert.NotNil(t, ms.DeleteByID(ctx, "test_memory_valid", "1234"))
+	assert.NotNil(t, ms.DeleteAll(ctx, "", 1))
+	assert.NotNil(t, ms.GetByQuery(ctx, nil, &TestObjectHandler{}))
+
+}
+
+func TestMemoryStoreQuery(t *testing.T) {
+
+	colName := "test_memory_query"
+	ctx := context.Background()
+	ms := NewMemoryStore()
+
+	for _, o := range []*MockedStoreObject{
+		NewTestObject("Portland", 1, 0.1),
+		NewTestObject("Seattle", 2, 0.2),
+		NewTestObject("Portland", 3, 0.3),
+		NewTestObject("Boise", 4, 0.4),
+	} {
+		assert.Nil(t, ms.Save(ctx, colName, o.ID, o))
+	}
+
+	list := []struct {
+		criteria []*Criterion
+		order    *Order
+		counts   []int
+	}{
+		{
+			criteria: []*Criterion{{Property: "name", Operator: "==", Value: "Portland"}},
+			order:    &Order{Property: "count"},
+			counts:   []int{1, 3},
+		},
+		{
+			criteria: []*Criterion{{Property: "count", Operator: ">", Value: 1}},
+			order:    &Order{Property: "count", Descending: true},
+			counts:   []int{4, 3, 2},
+		},
+		{
+			criteria: []*Criterion{
+				{Property: "value", Operator: ">=", Value: 0.2},
+				{Property: "value", Operator: "<", Value: 0.4},
+			},
+			order:  &Order{Property: "value"},
+			counts: []int{2, 3},
+		},
+		{
+			criteria: []*Criterion{{Property: "name", Operator: "in", Value: []string{"Boise", "Seattle"}}},
+			order:    &Order{Property: "name"},
+			counts:   []int{4, 2},
+		},
+		{
+			order:  &Order{Property: "count", Descending: true},
+			counts: []int{4, 3, 2, 1},
+		},
+	}
+
+	for _, c := range list {
+		h := &TestObjectHandler{Items: make([]*MockedStoreObject, 0)}
+		err := ms.GetByQuery(ctx, &QueryCriteria{
+			Collection: colName,
+			Criteria:   c.criteria,
+			OrderBy:    c.order,
+		}, h)
+		assert.Nil(t, err)
+
+		counts := make([]int, 0)
+		for _, item := range h.Items {
+			counts = append(counts, item.Count)
+		}
+		assert.Equal(t, c.counts, counts)
+	}
+
+	h := &TestObjectHandler{Items: make([]*MockedStoreObject, 0)}
+	err := ms.GetByQuery(ctx, &QueryCriteria{
+		Collection: colName,
+		Criteria:   []*Criterion{{Property: "name", Operator: "~", Value: "Boise"}},
+	}, h)
+	assert.NotNil(t, err)
+
+	err = ms.DeleteAll(ctx, colName, 1)
+	assert.Nil(t, err)
+
+	err = ms.GetByQuery(ctx, &QueryCriteria{Collection: colName}, h)
+	assert.Nil(t, err)
+	assert.Len(t, h.Items, 0)
+
+}
+
+func TestMemoryStoreArrayContains(t *testing.T) {
+
+	colName := "test_memory_array"
+	ctx := context.Background()
+	ms := NewMemoryStore()
+
+	type tagged struct {
+		Name string   `firestore:"name"`
+		Tags []string `firestore:"tags,omitempty"`
+		Skip string   `firestore:"-"`
+	}
+
+	assert.Nil(t, ms.Save(ctx, colName, "a1", &tagged{Name: "a", Tags: []string{"x", "y"}, Skip: "s"}))
+	assert.Nil(t, ms.Save(ctx, colName, "b1", &tagged{Name: "b", Tags: []string{"y"}}))
+	assert.Nil(t, ms.Save(ctx, colName, "c1", &tagged{Name: "c"}))
+
+	out := &tagged{}
+	assert.Nil(t, ms.GetByID(ctx, colName, "a1", out))
+	assert.Equal(t, "", out.Skip)
+	assert.Equal(t, []string{"x", "y"}, out.Tags)
+
+	h := &mapHandler{}
+	err := ms.GetByQuery(ctx, &QueryCriteria{
+		Collection: colName,
+		Criteria:   []*Criterion{{Property: "tags", Operator: "array-contains", Value: "y"}},
+	}, h)
+	assert.Nil(t, err)
+	assert.Len(t, h.items, 2)
+	assert.Equal(t, "a", h.items[0]["name"])
+
+}
+
+func TestMemoryStoreDeleteAllRecursive(t *testing.T) {
+
+	colName := "test_memory_recursive"
+	ctx := context.Background()
+	ms := NewMemoryStore()
+
+	parent := NewTestObject("John", 1, 0.1)
+	assert.Nil(t, ms.Save(ctx, colName, parent.ID, parent))
+
+	child := NewTestObject("Jane", 2, 0.2)
+	subCol := colName + "/" + parent.ID + "/children"
+	assert.Nil(t, ms.Save(ctx, subCol, child.ID, child))
+
+	progress := 0
+	err := ms.DeleteAll(ctx, colName, 1, Recursive(), DryRun(), Progress(func(deleted int) {
+		progress = deleted
+	}))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, progress)
+	assert.Nil(t, ms.GetByID(ctx, subCol, child.ID, &MockedStoreObject{}))
+
+	assert.Nil(t, ms.DeleteAll(ctx, colName, 1))
+	assert.Nil(t, ms.GetByID(ctx, subCol, child.ID, &MockedStoreObject{}))
+
+	assert.Nil(t, ms.DeleteAll(ctx, colName, 1, Recursive()))
+	err = ms.GetByID(ctx, subCol, child.ID, &MockedStoreObject{})
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+}
+
+func TestMemoryStorePath(t *testing.T) {
+
+	ctx := context.Background()
+	ms := NewMemoryStore()
+	obj := NewTestObject("John", 1, 0.1)
+
+	col := Path("users", "u1", "orders")
+	assert.Nil(t, ms.Save(ctx, col, obj.ID, obj))
+	assert.Nil(t, ms.GetByID(ctx, col, obj.ID, &MockedStoreObject{}))
+
+	err := ms.Save(ctx, Path("users", "u1"), obj.ID, obj)
+	assert.True(t, errors.Is(err, ErrInvalidPath))
+
+	err = ms.GetByQuery(ctx, &QueryCriteria{Collection: "users/u1"}, &TestObjectHandler{})
+	assert.True(t, errors.Is(err, ErrInvalidPath))
+
+}
+
+func TestMemoryStoreCollectionGroup(t *testing.T) {
+
+	ctx := context.Background()
+	ms := NewMemoryStore()
+
+	for _, uid := range []string{"u1", "u2"} {
+		obj := NewTestObject(uid, 1, 0.1)
+		assert.Nil(t, ms.Save(ctx, Path("users", uid, "orders"), "o1", obj))
+	}
+	assert.Nil(t, ms.Save(ctx, "orders", "o1", NewTestObject("top", 1, 0.1)))
+	assert.Nil(t, ms.Save(ctx, Path("users", "u1", "returns"), "r1", NewTestObject("u1", 1, 0.1)))
+
+	h := &PathObjectHandler{}
+	err := ms.GetByQuery(ctx, &QueryCriteria{
+		Collection:      "orders",
+		CollectionGroup: true,
+		Criteria:        []*Criterion{{Property: "name", Operator: ">=", Value: "u"}},
+	}, h)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"users/u1/orders/o1", "users/u2/orders/o1"}, h.Paths)
+	assert.Len(t, h.Items, 2)
+
+}
+
+type mapHandler struct {
+	items []map[string]interface{}
+}
+
+func (h *mapHandler) MakeNew() interface{} {
+	return &map[string]interface{}{}
+}
+
+func (h *mapHandler) Append(item interface{}) {
+	h.items = append(h.items, *item.(*map[string]interface{}))
+}
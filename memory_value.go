@@ -0,0 +1,465 @@
+package lighter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// memory values are limited to the same set of types Firestore stores:
+// nil, bool, int64, float64, string, []byte, time.Time,
+// []interface{} and map[string]interface{}
+
+var (
+	typeOfTime      = reflect.TypeOf(time.Time{})
+	typeOfByteSlice = reflect.TypeOf([]byte{})
+)
+
+// toMemoryDoc converts struct or map into document data,
+// server timestamp fields are set to now
+func toMemoryDoc(obj interface{}, now time.Time) (map[string]interface{}, error) {
+	v := reflect.ValueOf(obj)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, errors.New("object required")
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct && v.Kind() != reflect.Map {
+		return nil, fmt.Errorf("object must be a struct or map, got %s", v.Type())
+	}
+
+	val, err := toMemoryValue(v, now)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, ok := val.(map[string]interface{})
+	if !ok {
+		return map[string]interface{}{}, nil
+	}
+	return doc, nil
+}
+
+// toMemoryValue converts Go value into its stored representation,
+// server timestamp fields are set to now
+func toMemoryValue(v reflect.Value, now time.Time) (interface{}, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+
+	switch v.Type() {
+	case typeOfTime:
+		return v.Interface().(time.Time).UTC().Truncate(time.Microsecond), nil
+	case typeOfByteSlice:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return append([]byte{}, v.Bytes()...), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return toMemoryValue(v.Elem(), now)
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return int64(v.Uint()), nil
+	case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		u := v.Uint()
+		if u > math.MaxInt64 {
+			return nil, fmt.Errorf("value %d overflows int64", u)
+		}
+		return int64(u), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, nil
+		}
+		list := make([]interface{}, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			item, err := toMemoryValue(v.Index(i), now)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = item
+		}
+		return list, nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("map key type must be string, got %s", v.Type().Key())
+		}
+		if v.IsNil() {
+			return nil, nil
+		}
+		m := make(map[string]interface{}, v.Len())
+		for _, k := range v.MapKeys() {
+			item, err := toMemoryValue(v.MapIndex(k), now)
+			if err != nil {
+				return nil, err
+			}
+			m[k.String()] = item
+		}
+		return m, nil
+	case reflect.Struct:
+		m := map[string]interface{}{}
+		for _, f := range docFields(v.Type()) {
+			fv, ok := fieldByIndex(v, f.index)
+			if !ok || f.isMetadata() {
+				continue
+			}
+			if f.isServerTimestamp(fv) {
+				m[f.name] = now.UTC().Truncate(time.Microsecond)
+				continue
+			}
+			if f.omitEmpty && isEmptyValue(fv) {
+				continue
+			}
+			item, err := toMemoryValue(fv, now)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %v", v.Type(), f.name, err)
+			}
+			m[f.name] = item
+		}
+		return m, nil
+	}
+
+	return nil, fmt.Errorf("unsupported type: %s", v.Type())
+}
+
+// fromMemoryDoc loads document data into the pointer passed in
+func fromMemoryDoc(doc map[string]interface{}, in interface{}) error {
+	v := reflect.ValueOf(in)
+	if !v.IsValid() || v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.New("nil or not a pointer")
+	}
+	return fromMemoryValue(v.Elem(), doc)
+}
+
+// fromMemoryValue sets settable v from stored representation
+func fromMemoryValue(v reflect.Value, src interface{}) error {
+	typeErr := func() error {
+		return fmt.Errorf("cannot set type %s to %T", v.Type(), src)
+	}
+
+	if src == nil {
+		switch v.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return nil
+	}
+
+	switch v.Type() {
+	case typeOfTime:
+		t, ok := src.(time.Time)
+		if !ok {
+			return typeErr()
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case typeOfByteSlice:
+		b, ok := src.([]byte)
+		if !ok {
+			return typeErr()
+		}
+		v.SetBytes(append([]byte{}, b...))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return fromMemoryValue(v.Elem(), src)
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return fmt.Errorf("cannot set type %s", v.Type())
+		}
+		if !v.IsNil() && v.Elem().Kind() == reflect.Ptr {
+			return fromMemoryValue(v.Elem(), src)
+		}
+		v.Set(reflect.ValueOf(copyMemoryValue(src)))
+		return nil
+	case reflect.Bool:
+		b, ok := src.(bool)
+		if !ok {
+			return typeErr()
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := src.(int64)
+		if !ok {
+			return typeErr()
+		}
+		if v.OverflowInt(i) {
+			return fmt.Errorf("value %d overflows type %s", i, v.Type())
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, ok := src.(int64)
+		if !ok {
+			return typeErr()
+		}
+		if i < 0 || v.OverflowUint(uint64(i)) {
+			return fmt.Errorf("value %d overflows type %s", i, v.Type())
+		}
+		v.SetUint(uint64(i))
+	case reflect.Float32, reflect.Float64:
+		switch x := src.(type) {
+		case float64:
+			v.SetFloat(x)
+		case int64:
+			v.SetFloat(float64(x))
+		default:
+			return typeErr()
+		}
+	case reflect.String:
+		s, ok := src.(string)
+		if !ok {
+			return typeErr()
+		}
+		v.SetString(s)
+	case reflect.Slice:
+		list, ok := src.([]interface{})
+		if !ok {
+			return typeErr()
+		}
+		s := reflect.MakeSlice(v.Type(), len(list), len(list))
+		for i, item := range list {
+			if err := fromMemoryValue(s.Index(i), item); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+	case reflect.Array:
+		list, ok := src.([]interface{})
+		if !ok {
+			return typeErr()
+		}
+		for i := 0; i < v.Len(); i++ {
+			if i < len(list) {
+				if err := fromMemoryValue(v.Index(i), list[i]); err != nil {
+					return err
+				}
+				continue
+			}
+			v.Index(i).Set(reflect.Zero(v.Type().Elem()))
+		}
+	case reflect.Map:
+		m, ok := src.(map[string]interface{})
+		if !ok {
+			return typeErr()
+		}
+		if v.Type().Key().Kind() != reflect.String {
+			return errors.New("map key type is not string")
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		for k, item := range m {
+			el := reflect.New(v.Type().Elem()).Elem()
+			if err := fromMemoryValue(el, item); err != nil {
+				return err
+			}
+			v.SetMapIndex(reflect.ValueOf(k).Convert(v.Type().Key()), el)
+		}
+	case reflect.Struct:
+		m, ok := src.(map[string]interface{})
+		if !ok {
+			return typeErr()
+		}
+		for _, f := range docFields(v.Type()) {
+			item, ok := m[f.name]
+			if !ok {
+				continue
+			}
+			fv, err := settableField(v, f.index)
+			if err != nil {
+				return err
+			}
+			if err := fromMemoryValue(fv, item); err != nil {
+				return fmt.Errorf("%s.%s: %v", v.Type(), f.name, err)
+			}
+		}
+	default:
+		return fmt.Errorf("cannot set type %s", v.Type())
+	}
+
+	return nil
+}
+
+// copyMemoryValue deep copies stored value so callers can't mutate the store
+func copyMemoryValue(src interface{}) interface{} {
+	switch x := src.(type) {
+	case []byte:
+		return append([]byte{}, x...)
+	case []interface{}:
+		list := make([]interface{}, len(x))
+		for i, item := range x {
+			list[i] = copyMemoryValue(item)
+		}
+		return list
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, item := range x {
+			m[k] = copyMemoryValue(item)
+		}
+		return m
+	}
+	return src
+}
+
+// memoryValueAt returns the value at dotted field path
+func memoryValueAt(doc map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = doc
+	for _, part := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// memoryTypeOrder follows Firestore ordering of values of different types
+func memoryTypeOrder(v interface{}) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case int64, float64:
+		return 2
+	case time.Time:
+		return 3
+	case string:
+		return 4
+	case []byte:
+		return 5
+	case []interface{}:
+		return 7
+	case map[string]interface{}:
+		return 8
+	}
+	return 6
+}
+
+// compareMemoryValues returns -1, 0 or 1 when a is less, equal or greater than b
+func compareMemoryValues(a, b interface{}) int {
+	ta, tb := memoryTypeOrder(a), memoryTypeOrder(b)
+	if ta != tb {
+		return compareInts(int64(ta), int64(tb))
+	}
+
+	switch x := a.(type) {
+	case bool:
+		y := b.(bool)
+		if x == y {
+			return 0
+		}
+		if !x {
+			return -1
+		}
+		return 1
+	case int64:
+		if y, ok := b.(int64); ok {
+			return compareInts(x, y)
+		}
+		return compareFloats(float64(x), b.(float64))
+	case float64:
+		if y, ok := b.(int64); ok {
+			return compareFloats(x, float64(y))
+		}
+		return compareFloats(x, b.(float64))
+	case time.Time:
+		y := b.(time.Time)
+		if x.Before(y) {
+			return -1
+		}
+		if x.After(y) {
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(x, b.(string))
+	case []byte:
+		return bytes.Compare(x, b.([]byte))
+	case []interface{}:
+		y := b.([]interface{})
+		for i := 0; i < len(x) && i < len(y); i++ {
+			if c := compareMemoryValues(x[i], y[i]); c != 0 {
+				return c
+			}
+		}
+		return compareInts(int64(len(x)), int64(len(y)))
+	case map[string]interface{}:
+		y := b.(map[string]interface{})
+		xk, yk := sortedKeys(x), sortedKeys(y)
+		for i := 0; i < len(xk) && i < len(yk); i++ {
+			if c := strings.Compare(xk[i], yk[i]); c != 0 {
+				return c
+			}
+			if c := compareMemoryValues(x[xk[i]], y[yk[i]]); c != 0 {
+				return c
+			}
+		}
+		return compareInts(int64(len(xk)), int64(len(yk)))
+	}
+
+	return 0
+}
+
+func compareInts(a, b int64) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
+
+// compareFloats orders NaN before all other numbers as Firestore does
+func compareFloats(a, b float64) int {
+	switch {
+	case math.IsNaN(a) && math.IsNaN(b):
+		return 0
+	case math.IsNaN(a):
+		return -1
+	case math.IsNaN(b):
+		return 1
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
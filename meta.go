@@ -1,6 +1,7 @@
 package lighter
 
 import (
+	"context"
 	"net/http"
 	"os"
 	"strings"
@@ -8,6 +9,11 @@ import (
 	m "cloud.google.com/go/compute/metadata"
 )
 
+const (
+	emulatorHostKey   = "FIRESTORE_EMULATOR_HOST"
+	emulatorProjectID = "lighter-emulator"
+)
+
 var (
 	agentName = "lighter"
 
@@ -36,6 +42,10 @@ func getProjectID() (id string, err error) {
 			return strings.TrimSpace(val), nil
 		}
 	}
+	// emulator does not validate project so there is no need for metadata lookup
+	if val := os.Getenv(emulatorHostKey); val != "" {
+		return emulatorProjectID, nil
+	}
 	return getClient().ProjectID()
 }
 
@@ -50,3 +60,17 @@ func (t userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error)
 	req.Header.Set("User-Agent", t.userAgent)
 	return t.base.RoundTrip(req)
 }
+
+// emulatorCreds authenticates as the emulator admin, same as the Firestore
+// client does when FIRESTORE_EMULATOR_HOST is set
+type emulatorCreds struct{}
+
+// GetRequestMetadata implements the grpc credentials.PerRPCCredentials interface
+func (c emulatorCreds) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer owner"}, nil
+}
+
+// RequireTransportSecurity implements the grpc credentials.PerRPCCredentials interface
+func (c emulatorCreds) RequireTransportSecurity() bool {
+	return false
+}
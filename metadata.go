@@ -0,0 +1,168 @@
+package lighter
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+const (
+	// tagDocID sets string field to the loaded document ID
+	tagDocID = "docID"
+	// tagCreateTime sets time field to the time the loaded document was created
+	tagCreateTime = "createTime"
+	// tagUpdateTime sets time field to the time the loaded document was last changed
+	tagUpdateTime = "updateTime"
+	// tagReadTime sets time field to the time the loaded document was read
+	tagReadTime = "readTime"
+)
+
+// Metadata describes the stored document an object was loaded from
+type Metadata struct {
+	// ID is the document ID
+	ID string
+	// Path is the document path relative to the database root
+	// (e.g. users/{uid}/orders/{oid})
+	Path string
+	// CreateTime is the time the document was created
+	CreateTime time.Time
+	// UpdateTime is the time the document was last changed
+	UpdateTime time.Time
+	// ReadTime is the time the document was read
+	ReadTime time.Time
+}
+
+// MetadataReceiver can be implemented by loaded objects to receive
+// the metadata of the document they were loaded from
+type MetadataReceiver interface {
+	SetMetadata(m *Metadata)
+}
+
+// isMetadataTag reports whether lighter tag option opt marks read-only metadata field
+func isMetadataTag(opt string) bool {
+	switch opt {
+	case tagDocID, tagCreateTime, tagUpdateTime, tagReadTime:
+		return true
+	}
+	return false
+}
+
+// isMetadata reports whether field is filled from document metadata
+// and therefore never written
+func (f docField) isMetadata() bool {
+	for opt := range f.lighter {
+		if isMetadataTag(opt) {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshotMetadata returns metadata of Firestore document
+func snapshotMetadata(doc *firestore.DocumentSnapshot) *Metadata {
+	return &Metadata{
+		ID:         doc.Ref.ID,
+		Path:       relativePath(doc.Ref.Path),
+		CreateTime: doc.CreateTime,
+		UpdateTime: doc.UpdateTime,
+		ReadTime:   doc.ReadTime,
+	}
+}
+
+// metaField is struct field tagged with one of the metadata lighter tag options
+type metaField struct {
+	index []int
+	opt   string
+}
+
+// metadataFields returns fields of struct type t tagged with metadata lighter
+// tag options. Unlike docFields it includes fields hidden from Firestore
+// using `firestore:"-"`
+func metadataFields(t reflect.Type) []metaField {
+	list := make([]metaField, 0)
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+
+		if sf.Anonymous && sf.Tag.Get(lighterTag) == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for _, f := range metadataFields(ft) {
+					f.index = append([]int{i}, f.index...)
+					list = append(list, f)
+				}
+				continue
+			}
+		}
+
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		for _, opt := range strings.Split(sf.Tag.Get(lighterTag), ",") {
+			if isMetadataTag(opt) {
+				list = append(list, metaField{index: []int{i}, opt: opt})
+			}
+		}
+	}
+	return list
+}
+
+// applyMetadata fills the metadata tagged fields of the struct obj points to
+// and passes m to obj when it implements MetadataReceiver
+func applyMetadata(obj interface{}, m *Metadata) error {
+	if r, ok := obj.(MetadataReceiver); ok {
+		r.SetMetadata(m)
+	}
+
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	v = v.Elem()
+
+	for _, f := range metadataFields(v.Type()) {
+		fv, err := settableField(v, f.index)
+		if err != nil {
+			return err
+		}
+
+		var val interface{}
+		switch f.opt {
+		case tagDocID:
+			val = m.ID
+		case tagCreateTime:
+			val = m.CreateTime
+		case tagUpdateTime:
+			val = m.UpdateTime
+		case tagReadTime:
+			val = m.ReadTime
+		}
+
+		if err := setMetadataField(fv, val); err != nil {
+			return fmt.Errorf("%s: lighter:\"%s\": %v", v.Type(), f.opt, err)
+		}
+	}
+	return nil
+}
+
+// setMetadataField sets fv to val, time fields may also be time pointers
+func setMetadataField(fv reflect.Value, val interface{}) error {
+	rv := reflect.ValueOf(val)
+	switch {
+	case fv.Type() == rv.Type():
+		fv.Set(rv)
+	case fv.Kind() == reflect.Ptr && fv.Type().Elem() == rv.Type():
+		p := reflect.New(rv.Type())
+		p.Elem().Set(rv)
+		fv.Set(p)
+	default:
+		return fmt.Errorf("cannot set %s field to %s", fv.Type(), rv.Type())
+	}
+	return nil
+}
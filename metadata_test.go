@@ -0,0 +1,125 @@
+package lighter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type metaEmbedded struct {
+	Updated *time.Time `firestore:"-" lighter:"updateTime"`
+}
+
+type metaObject struct {
+	metaEmbedded
+	DocID   string    `firestore:"-" lighter:"docID"`
+	Name    string    `firestore:"name"`
+	Created time.Time `firestore:"created" lighter:"createTime"`
+
+	meta *Metadata
+}
+
+func (o *metaObject) SetMetadata(m *Metadata) {
+	o.meta = m
+}
+
+func TestApplyMetadata(t *testing.T) {
+
+	now := time.Now()
+	obj := &metaObject{}
+	err := applyMetadata(obj, &Metadata{
+		ID:         "id1",
+		Path:       "col/id1",
+		CreateTime: now.Add(-time.Hour),
+		UpdateTime: now,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "id1", obj.DocID)
+	assert.Equal(t, now.Add(-time.Hour), obj.Created)
+	assert.Equal(t, now, *obj.Updated)
+	assert.Equal(t, "col/id1", obj.meta.Path)
+
+	// objects without metadata fields are left as is
+	assert.Nil(t, applyMetadata(NewTestObject("John", 1, 0.1), &Metadata{ID: "id1"}))
+
+	type wrongType struct {
+		Created string `firestore:"-" lighter:"createTime"`
+	}
+	assert.NotNil(t, applyMetadata(&wrongType{}, &Metadata{}))
+
+}
+
+func TestMetadataNotWritten(t *testing.T) {
+
+	data := toWriteData(&metaObject{DocID: "id1", Name: "John", Created: time.Now()}, false)
+	m, ok := data.(map[string]interface{})
+	assert.True(t, ok)
+	assert.Equal(t, map[string]interface{}{"name": "John"}, m)
+
+	doc, err := toMemoryDoc(&metaObject{Name: "John", Created: time.Now()}, time.Now())
+	assert.Nil(t, err)
+	assert.NotContains(t, doc, "created")
+
+}
+
+func TestMemoryStoreMetadata(t *testing.T) {
+
+	ctx := context.Background()
+	ms := NewMemoryStore()
+	col := Path("users", "u1", "orders")
+
+	assert.Nil(t, ms.Save(ctx, col, "o1", &metaObject{Name: "John"}))
+
+	obj := &metaObject{}
+	assert.Nil(t, ms.GetByID(ctx, col, "o1", obj))
+	assert.Equal(t, "o1", obj.DocID)
+	assert.Equal(t, "users/u1/orders/o1", obj.meta.Path)
+
+	h := &metaHandler{}
+	assert.Nil(t, ms.GetByQuery(ctx, &QueryCriteria{Collection: col}, h))
+	assert.Len(t, h.items, 1)
+	assert.Equal(t, "o1", h.items[0].DocID)
+
+}
+
+func TestGetByIDMetadata(t *testing.T) {
+
+	requireStore(t)
+
+	colName := "test_metadata"
+	ctx := context.Background()
+
+	before := time.Now().Add(-time.Minute)
+	assert.Nil(t, store.Save(ctx, colName, "m1", &metaObject{Name: "John"}))
+
+	obj := &metaObject{}
+	err := store.GetByID(ctx, colName, "m1", obj)
+	assert.Nil(t, err)
+	assert.Equal(t, "m1", obj.DocID)
+	assert.True(t, obj.Created.After(before))
+	assert.False(t, obj.Updated.Before(obj.Created))
+	assert.False(t, obj.meta.ReadTime.IsZero())
+
+	h := &metaHandler{}
+	err = store.GetByQuery(ctx, &QueryCriteria{Collection: colName}, h)
+	assert.Nil(t, err)
+	assert.Len(t, h.items, 1)
+	assert.Equal(t, obj.Created, h.items[0].Created)
+
+	assert.Nil(t, store.DeleteByID(ctx, colName, "m1"))
+
+}
+
+type metaHandler struct {
+	items []*metaObject
+}
+
+func (h *metaHandler) MakeNew() interface{} {
+	return &metaObject{}
+}
+
+func (h *metaHandler) Append(item interface{}) {
+	h.items = append(h.items, item.(*metaObject))
+}
@@ -38,3 +38,15 @@ func (t *TestObjectHandler) MakeNew() interface{} {
 func (t *TestObjectHandler) Append(item interface{}) {
 	t.Items = append(t.Items, item.(*MockedStoreObject))
 }
+
+// PathObjectHandler is a test implementation of the PathResultHandler interface
+type PathObjectHandler struct {
+	TestObjectHandler
+	Paths []string
+}
+
+// AppendWithPath adds newly loaded item to results along with its path
+func (t *PathObjectHandler) AppendWithPath(item interface{}, path string) {
+	t.Append(item)
+	t.Paths = append(t.Paths, path)
+}
@@ -0,0 +1,88 @@
+package lighter
+
+import (
+	"fmt"
+	"strings"
+)
+
+const pathSeparator = "/"
+
+// Path builds slash separated path to a nested collection which can be used
+// anywhere lighter accepts collection name, for example
+// Path("users", uid, "orders") results in users/{uid}/orders
+func Path(segments ...string) string {
+	return strings.Join(segments, pathSeparator)
+}
+
+// SplitDocPath splits path to a document (e.g. users/{uid}/orders/{oid})
+// into its collection path and document ID
+func SplitDocPath(path string) (collection, id string, err error) {
+
+	segments := strings.Split(path, pathSeparator)
+	if len(segments)%2 != 0 {
+		return "", "", fmt.Errorf("%w: document path must have even number of segments: '%s'", ErrInvalidPath, path)
+	}
+
+	i := strings.LastIndex(path, pathSeparator)
+	collection, id = path[:i], path[i+1:]
+
+	if err := validateDocArgs(collection, id); err != nil {
+		return "", "", err
+	}
+
+	return collection, id, nil
+
+}
+
+// relativePath strips the project and database prefix from the full
+// Firestore document path (projects/{p}/databases/{db}/documents/...)
+func relativePath(path string) string {
+	const docsPrefix = "/documents/"
+	if i := strings.Index(path, docsPrefix); i >= 0 {
+		return path[i+len(docsPrefix):]
+	}
+	return path
+}
+
+// validateQueryCollection checks query collection which must be a single
+// collection ID in case of collection group query
+func validateQueryCollection(q *QueryCriteria) error {
+
+	if !q.CollectionGroup {
+		return validateCollection(q.Collection)
+	}
+
+	if q.Collection == "" {
+		return ErrCollectionRequired
+	}
+
+	if strings.Contains(q.Collection, pathSeparator) {
+		return fmt.Errorf("%w: collection group must be a collection ID: '%s'", ErrInvalidPath, q.Collection)
+	}
+
+	return nil
+
+}
+
+// validateCollection checks collection name or path to a nested collection
+// which must have odd number of non-empty segments
+func validateCollection(collection string) error {
+
+	if collection == "" {
+		return ErrCollectionRequired
+	}
+
+	segments := strings.Split(collection, pathSeparator)
+	if len(segments)%2 == 0 {
+		return fmt.Errorf("%w: collection path must have odd number of segments: '%s'", ErrInvalidPath, collection)
+	}
+
+	for _, s := range segments {
+		if s == "" {
+			return fmt.Errorf("%w: empty segment in collection path: '%s'", ErrInvalidPath, collection)
+		}
+	}
+
+	return nil
+
+}
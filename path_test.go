@@ -0,0 +1,49 @@
+package lighter
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPath(t *testing.T) {
+	assert.Equal(t, "users", Path("users"))
+	assert.Equal(t, "users/u1/orders", Path("users", "u1", "orders"))
+}
+
+func TestSplitDocPath(t *testing.T) {
+
+	col, id, err := SplitDocPath("users/u1/orders/o1")
+	assert.Nil(t, err)
+	assert.Equal(t, "users/u1/orders", col)
+	assert.Equal(t, "o1", id)
+
+	_, _, err = SplitDocPath("users/u1/orders")
+	assert.True(t, errors.Is(err, ErrInvalidPath))
+
+	_, _, err = SplitDocPath("users/1")
+	assert.True(t, errors.Is(err, ErrInvalidID))
+
+}
+
+func TestValidateCollection(t *testing.T) {
+	assert.Nil(t, validateCollection("users"))
+	assert.Nil(t, validateCollection(Path("users", "u1", "orders")))
+	assert.True(t, errors.Is(validateCollection(""), ErrCollectionRequired))
+	assert.True(t, errors.Is(validateCollection("users/u1"), ErrInvalidPath))
+	assert.True(t, errors.Is(validateCollection("users//orders"), ErrInvalidPath))
+}
+
+func TestRelativePath(t *testing.T) {
+	assert.Equal(t, "users/u1/orders/o1",
+		relativePath("projects/p1/databases/(default)/documents/users/u1/orders/o1"))
+	assert.Equal(t, "users/u1", relativePath("users/u1"))
+}
+
+func TestValidateQueryCollection(t *testing.T) {
+	assert.Nil(t, validateQueryCollection(&QueryCriteria{Collection: "orders", CollectionGroup: true}))
+	assert.True(t, errors.Is(validateQueryCollection(&QueryCriteria{CollectionGroup: true}), ErrCollectionRequired))
+	err := validateQueryCollection(&QueryCriteria{Collection: "users/u1/orders", CollectionGroup: true})
+	assert.True(t, errors.Is(err, ErrInvalidPath))
+}
@@ -0,0 +1,196 @@
+package lighter
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultRetryAttempts    = 3
+	defaultRetryBaseBackoff = 100 * time.Millisecond
+	defaultRetryMaxBackoff  = 5 * time.Second
+)
+
+// retryMode tells whether operation is retried as a whole, it is declared
+// on the Op of each operation. Operations on multiple documents use the zero
+// value, they retry each of their batch commits instead so that results
+// are not appended or counted twice
+type retryMode int
+
+const (
+	retryNone retryMode = iota
+	retryIdempotent
+	retryNonIdempotent
+)
+
+// attemptKey is the context key of the attempt run by the retry policy
+type attemptKey struct{}
+
+// retryAttempt tracks single attempt of retried operation
+type retryAttempt struct {
+	// transaction is set when the attempt ran Firestore transaction
+	// which already retries on contention
+	transaction bool
+}
+
+// RetryPolicy configures retrying of store operations failed with transient errors.
+// Zero values of MaxAttempts, BaseBackoff and MaxBackoff are replaced with defaults
+type RetryPolicy struct {
+	// MaxAttempts is the max number of attempts including the first one (default 3)
+	MaxAttempts int
+	// BaseBackoff is the delay before the first retry, doubled on every
+	// next retry (default 100ms)
+	BaseBackoff time.Duration
+	// MaxBackoff caps the delay between retries (default 5s)
+	MaxBackoff time.Duration
+	// Jitter is the fraction (0-1) by which each delay is randomly shortened
+	// to spread retries of concurrent clients, 0 disables jitter
+	Jitter float64
+	// Retryable reports whether error with gRPC code should be retried,
+	// DefaultRetryable is used when not set
+	Retryable func(code codes.Code) bool
+	// NonIdempotent enables retries of operations which may not be safe
+	// to repeat when the failed attempt was applied (e.g. Create or Increment)
+	NonIdempotent bool
+	// OnRetry is called before each retry with the number of the failed
+	// attempt, its error and the delay before the next one
+	OnRetry func(ctx context.Context, op Op, attempt int, err error, delay time.Duration)
+}
+
+// DefaultRetryable reports whether gRPC code indicates transient error:
+// Unavailable, DeadlineExceeded, Aborted or ResourceExhausted
+func DefaultRetryable(code codes.Code) bool {
+	switch code {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Aborted, codes.ResourceExhausted:
+		return true
+	}
+	return false
+}
+
+// WithRetryPolicy retries store operations failed with transient errors.
+// Only idempotent operations are retried unless NonIdempotent is set on p
+func WithRetryPolicy(p *RetryPolicy) StoreOption {
+	return func(o *storeOptions) {
+		o.retryPolicy = p
+	}
+}
+
+// backoff returns the delay after failed attempt
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	base, max := p.BaseBackoff, p.MaxBackoff
+	if base <= 0 {
+		base = defaultRetryBaseBackoff
+	}
+	if max <= 0 {
+		max = defaultRetryMaxBackoff
+	}
+
+	d := base
+	for i := 1; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+
+	if p.Jitter > 0 {
+		j := p.Jitter
+		if j > 1 {
+			j = 1
+		}
+		d -= time.Duration(rand.Float64() * j * float64(d))
+	}
+	return d
+}
+
+// retryable reports whether err should be retried
+func (p *RetryPolicy) retryable(err error) bool {
+	code := status.Code(err)
+	if p.Retryable != nil {
+		return p.Retryable(code)
+	}
+	return DefaultRetryable(code)
+}
+
+// retryOp runs operation fn retrying it according to the retry policy
+// when the operation is retryable as a whole
+func (o *storeOptions) retryOp(ctx context.Context, op Op, fn func(ctx context.Context) error) error {
+	p := o.retryPolicy
+	if p == nil || op.retry == retryNone || (op.retry == retryNonIdempotent && !p.NonIdempotent) {
+		return fn(ctx)
+	}
+	return p.run(ctx, op, fn)
+}
+
+// writeRetry returns the retry mode of single document write, which is not
+// idempotent when it also archives the stored revision into history
+func (o *storeOptions) writeRetry() retryMode {
+	if o.history {
+		return retryNonIdempotent
+	}
+	return retryIdempotent
+}
+
+// retryCommit runs batch commit fn of the operation of ctx
+// retrying it according to the retry policy
+func (o *storeOptions) retryCommit(ctx context.Context, fn func(ctx context.Context) error) error {
+	if o.retryPolicy == nil {
+		return fn(ctx)
+	}
+	return o.retryPolicy.run(ctx, opFromContext(ctx), fn)
+}
+
+// run calls fn until it succeeds, fails with error which is not retryable,
+// the max attempts are reached or ctx is done
+func (p *RetryPolicy) run(ctx context.Context, op Op, fn func(ctx context.Context) error) error {
+
+	max := p.MaxAttempts
+	if max <= 0 {
+		max = defaultRetryAttempts
+	}
+
+	for attempt := 1; ; attempt++ {
+		a := &retryAttempt{}
+		err := fn(context.WithValue(ctx, attemptKey{}, a))
+		if err == nil || attempt >= max || !p.retryable(err) {
+			return err
+		}
+		// transaction aborted due to contention was already run again by Firestore
+		if a.transaction && status.Code(err) == codes.Aborted {
+			return err
+		}
+
+		delay := p.backoff(attempt)
+		trace.FromContext(ctx).Annotate([]trace.Attribute{
+			trace.Int64Attribute("lighter.attempt", int64(attempt)),
+			trace.StringAttribute("lighter.delay", delay.String()),
+		}, "retrying: "+err.Error())
+		if p.OnRetry != nil {
+			p.OnRetry(ctx, op, attempt, err, delay)
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		}
+	}
+
+}
+
+// runTransaction runs Firestore transaction marking the current attempt
+// of retried operation so that it is not run again when aborted
+func (d *Store) runTransaction(ctx context.Context, fn func(ctx context.Context, tx *firestore.Transaction) error, opts ...firestore.TransactionOption) error {
+	if a, ok := ctx.Value(attemptKey{}).(*retryAttempt); ok {
+		a.transaction = true
+	}
+	return d.client.RunTransaction(ctx, fn, opts...)
+}
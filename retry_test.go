@@ -0,0 +1,212 @@
+package lighter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// failing returns interceptor failing the first n attempts of every operation with code
+func failing(n int, code codes.Code) (Interceptor, *int) {
+	calls := 0
+	return func(ctx context.Context, op Op, next func(ctx context.Context) error) error {
+		calls++
+		if calls <= n {
+			return status.Error(code, "injected")
+		}
+		return next(ctx)
+	}, &calls
+}
+
+func TestRetryBackoff(t *testing.T) {
+
+	p := &RetryPolicy{BaseBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	for i, want := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		assert.Equal(t, want*time.Millisecond, p.backoff(i+1))
+	}
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := p.backoff(2)
+		assert.True(t, d > 100*time.Millisecond && d <= 200*time.Millisecond, d)
+	}
+
+	// defaults
+	assert.Equal(t, defaultRetryBaseBackoff, (&RetryPolicy{}).backoff(1))
+	assert.Equal(t, defaultRetryMaxBackoff, (&RetryPolicy{}).backoff(100))
+
+}
+
+func TestDefaultRetryable(t *testing.T) {
+	assert.True(t, DefaultRetryable(codes.Unavailable))
+	assert.True(t, DefaultRetryable(codes.DeadlineExceeded))
+	assert.True(t, DefaultRetryable(codes.Aborted))
+	assert.True(t, DefaultRetryable(codes.ResourceExhausted))
+	assert.False(t, DefaultRetryable(codes.NotFound))
+	assert.False(t, DefaultRetryable(codes.InvalidArgument))
+	assert.False(t, DefaultRetryable(codes.OK))
+}
+
+func TestRetryPolicy(t *testing.T) {
+
+	ctx := context.Background()
+	obj := NewTestObject("John", 1, 0.1)
+	retries := make([]int, 0)
+	policy := &RetryPolicy{
+		MaxAttempts: 3,
+		BaseBackoff: time.Millisecond,
+		OnRetry: func(ctx context.Context, op Op, attempt int, err error, delay time.Duration) {
+			assert.Equal(t, "Save", op.Name)
+			assert.Equal(t, codes.Unavailable, status.Code(err))
+			retries = append(retries, attempt)
+		},
+	}
+
+	// transient errors are retried until the operation succeeds
+	i, calls := failing(2, codes.Unavailable)
+	ms := NewMemoryStore(WithInterceptors(i), WithRetryPolicy(policy))
+	assert.Nil(t, ms.Save(ctx, "retry", obj.ID, obj))
+	assert.Equal(t, 3, *calls)
+	assert.Equal(t, []int{1, 2}, retries)
+
+	// the last error is returned after max attempts
+	i, calls = failing(5, codes.Unavailable)
+	ms = NewMemoryStore(WithInterceptors(i), WithRetryPolicy(policy))
+	err := ms.Save(ctx, "retry", obj.ID, obj)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 3, *calls)
+
+	// other errors are not retried
+	i, calls = failing(1, codes.InvalidArgument)
+	ms = NewMemoryStore(WithInterceptors(i), WithRetryPolicy(policy))
+	assert.NotNil(t, ms.Save(ctx, "retry", obj.ID, obj))
+	assert.Equal(t, 1, *calls)
+
+	// queries are not retried as a whole
+	i, calls = failing(1, codes.Unavailable)
+	ms = NewMemoryStore(WithInterceptors(i), WithRetryPolicy(policy))
+	assert.NotNil(t, ms.GetByQuery(ctx, &QueryCriteria{Collection: "retry"}, &TestObjectHandler{}))
+	assert.Equal(t, 1, *calls)
+
+	// custom predicate
+	i, calls = failing(1, codes.Internal)
+	ms = NewMemoryStore(WithInterceptors(i), WithRetryPolicy(&RetryPolicy{
+		BaseBackoff: time.Millisecond,
+		Retryable:   func(code codes.Code) bool { return code == codes.Internal },
+	}))
+	assert.Nil(t, ms.Save(ctx, "retry", obj.ID, obj))
+	assert.Equal(t, 2, *calls)
+
+}
+
+func TestRetryNonIdempotent(t *testing.T) {
+
+	ctx := context.Background()
+	op := Op{Name: "Create", Collection: "retry", ID: "id1", retry: retryNonIdempotent}
+
+	i, calls := failing(1, codes.Unavailable)
+	o := makeStoreOptions([]StoreOption{WithInterceptors(i), WithRetryPolicy(&RetryPolicy{BaseBackoff: time.Millisecond})})
+	assert.NotNil(t, o.intercept(ctx, op, func(ctx context.Context) error { return nil }))
+	assert.Equal(t, 1, *calls)
+
+	i, calls = failing(1, codes.Unavailable)
+	o = makeStoreOptions([]StoreOption{WithInterceptors(i), WithRetryPolicy(&RetryPolicy{BaseBackoff: time.Millisecond, NonIdempotent: true})})
+	assert.Nil(t, o.intercept(ctx, op, func(ctx context.Context) error { return nil }))
+	assert.Equal(t, 2, *calls)
+
+	// field updates may hold transforms
+	var updated Op
+	st := &Store{opts: makeStoreOptions([]StoreOption{WithInterceptors(
+		func(ctx context.Context, op Op, next func(ctx context.Context) error) error {
+			updated = op
+			return nil
+		},
+	)})}
+	assert.Nil(t, st.UpdateFields(ctx, "retry", "id1", map[string]interface{}{"count": firestore.Increment(1)}))
+	assert.Equal(t, retryNonIdempotent, updated.retry)
+
+	// writes archiving history are not idempotent
+	assert.Equal(t, retryIdempotent, makeStoreOptions(nil).writeRetry())
+	assert.Equal(t, retryNonIdempotent, makeStoreOptions([]StoreOption{WithHistory(0)}).writeRetry())
+
+}
+
+func TestRetryTransaction(t *testing.T) {
+
+	ctx := context.Background()
+	o := makeStoreOptions([]StoreOption{WithRetryPolicy(&RetryPolicy{BaseBackoff: time.Millisecond})})
+	op := Op{Name: "Save", Collection: "retry", ID: "id1", retry: retryIdempotent}
+
+	// aborted transaction was already retried by Firestore
+	calls := 0
+	err := o.intercept(ctx, op, func(ctx context.Context) error {
+		calls++
+		ctx.Value(attemptKey{}).(*retryAttempt).transaction = true
+		return status.Error(codes.Aborted, "injected")
+	})
+	assert.Equal(t, codes.Aborted, status.Code(err))
+	assert.Equal(t, 1, calls)
+
+	// other transient errors of transaction are retried
+	calls = 0
+	err = o.intercept(ctx, op, func(ctx context.Context) error {
+		calls++
+		ctx.Value(attemptKey{}).(*retryAttempt).transaction = true
+		if calls == 1 {
+			return status.Error(codes.Unavailable, "injected")
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, calls)
+
+}
+
+func TestRetryCommit(t *testing.T) {
+
+	var retried Op
+	o := makeStoreOptions([]StoreOption{WithRetryPolicy(&RetryPolicy{
+		BaseBackoff: time.Millisecond,
+		OnRetry: func(ctx context.Context, op Op, attempt int, err error, delay time.Duration) {
+			retried = op
+		},
+	})})
+
+	commits := 0
+	err := o.intercept(context.Background(), Op{Name: "DeleteAll", Collection: "retry"}, func(ctx context.Context) error {
+		return o.retryCommit(ctx, func(ctx context.Context) error {
+			commits++
+			if commits == 1 {
+				return status.Error(codes.Aborted, "injected")
+			}
+			return nil
+		})
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, commits)
+	assert.Equal(t, Op{Name: "DeleteAll", Collection: "retry"}, retried)
+
+}
+
+func TestRetryCanceled(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	i, calls := failing(5, codes.Unavailable)
+	ms := NewMemoryStore(WithInterceptors(i), WithRetryPolicy(&RetryPolicy{
+		BaseBackoff: time.Hour,
+		OnRetry: func(ctx context.Context, op Op, attempt int, err error, delay time.Duration) {
+			cancel()
+		},
+	}))
+
+	obj := NewTestObject("John", 1, 0.1)
+	err := ms.Save(ctx, "retry", obj.ID, obj)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 1, *calls)
+
+}
@@ -3,26 +3,192 @@ package lighter
 import (
 	"context"
 	"errors"
-	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // Save inserts or updates by ID
 func (d *Store) Save(ctx context.Context, collection string, id string, obj interface{}) error {
+	return d.options().intercept(ctx, Op{Name: "Save", Collection: collection, ID: id, retry: d.options().writeRetry()}, func(ctx context.Context) error {
+		return recordWrite(ctx, d.save(ctx, collection, id, obj))
+	})
+}
+
+func (d *Store) save(ctx context.Context, collection string, id string, obj interface{}) error {
 
 	if obj == nil {
 		return errors.New("object required")
 	}
 
-	if !IsValidID(id) {
-		return fmt.Errorf("id must start with letter: '%s'", id)
+	if err := validateDocArgs(collection, id); err != nil {
+		return err
+	}
+
+	if err := d.options().beforeSave(ctx, "Save", collection, id, obj); err != nil {
+		return err
 	}
 
-	if collection == "" {
-		return errors.New("collection required")
+	ref := d.client.Collection(collection).Doc(id)
+
+	field := createdAtField(obj)
+	history := d.options().history
+	if field == "" && !history {
+		_, err := ref.Set(ctx, d.writeData(obj, time.Time{}))
+		return wrapError("Save", collection, id, err)
 	}
 
-	_, err := d.client.Collection(collection).Doc(id).Set(ctx, obj)
+	// the stored document is read in transaction so that its creation time
+	// is preserved and its previous state archived atomically with the write
+	err := d.runTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if history {
+			if err := d.archive(tx, doc, "Save"); err != nil {
+				return err
+			}
+		}
+		return tx.Set(ref, d.writeData(obj, storedTime(doc, field)))
+	})
+
+	return wrapError("Save", collection, id, err)
+
+}
+
+// Create inserts new object by ID, fails with ErrAlreadyExists when
+// document with that ID is already stored
+func (d *Store) Create(ctx context.Context, collection string, id string, obj interface{}) error {
+	return d.options().intercept(ctx, Op{Name: "Create", Collection: collection, ID: id, retry: retryNonIdempotent}, func(ctx context.Context) error {
+		return recordWrite(ctx, d.create(ctx, collection, id, obj))
+	})
+}
+
+func (d *Store) create(ctx context.Context, collection string, id string, obj interface{}) error {
+
+	if obj == nil {
+		return errors.New("object required")
+	}
 
-	return err
+	if err := validateDocArgs(collection, id); err != nil {
+		return err
+	}
+
+	if err := d.options().beforeSave(ctx, "Create", collection, id, obj); err != nil {
+		return err
+	}
+
+	_, err := d.client.Collection(collection).Doc(id).Create(ctx, d.writeData(obj, time.Time{}))
+
+	return wrapError("Create", collection, id, err)
+
+}
+
+// Add inserts obj under newly generated ID and returns that ID. When obj is
+// a pointer to struct with `firestore:"id"` string field, the ID is also
+// written into that field so the stored ID matches the document key
+func (d *Store) Add(ctx context.Context, collection string, obj interface{}) (id string, err error) {
+	id = d.options().idGenerator()
+	err = d.options().intercept(ctx, Op{Name: "Add", Collection: collection, ID: id, retry: retryNonIdempotent}, func(ctx context.Context) error {
+		return recordWrite(ctx, d.add(ctx, collection, id, obj))
+	})
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func (d *Store) add(ctx context.Context, collection string, id string, obj interface{}) error {
+
+	if obj == nil {
+		return errors.New("object required")
+	}
 
+	if err := validateDocArgs(collection, id); err != nil {
+		return err
+	}
+
+	setDocID(obj, id)
+
+	if err := d.options().beforeSave(ctx, "Add", collection, id, obj); err != nil {
+		return err
+	}
+
+	_, err := d.client.Collection(collection).Doc(id).Create(ctx, d.writeData(obj, time.Time{}))
+
+	return wrapError("Add", collection, id, err)
+
+}
+
+// SaveIfUnchanged updates by ID only when the stored document was not modified
+// or deleted (including soft delete) since lastUpdate (as returned by
+// GetByIDWithUpdateTime), otherwise fails with ErrConflict. Zero lastUpdate
+// requires that the document does not exist yet. Unlike Save, which replaces the
+// document, it updates the stored fields using the Firestore LastUpdateTime
+// precondition: struct fields left out by omitempty are deleted, but stored
+// fields which map obj does not have are kept
+func (d *Store) SaveIfUnchanged(ctx context.Context, collection string, id string, obj interface{}, lastUpdate time.Time) error {
+	return d.options().intercept(ctx, Op{Name: "SaveIfUnchanged", Collection: collection, ID: id, retry: retryNonIdempotent}, func(ctx context.Context) error {
+		return recordWrite(ctx, d.saveIfUnchanged(ctx, collection, id, obj, lastUpdate))
+	})
+}
+
+func (d *Store) saveIfUnchanged(ctx context.Context, collection string, id string, obj interface{}, lastUpdate time.Time) error {
+
+	if obj == nil {
+		return errors.New("object required")
+	}
+
+	if err := validateDocArgs(collection, id); err != nil {
+		return err
+	}
+
+	if err := d.options().beforeSave(ctx, "SaveIfUnchanged", collection, id, obj); err != nil {
+		return err
+	}
+
+	ref := d.client.Collection(collection).Doc(id)
+
+	if lastUpdate.IsZero() {
+		_, err := ref.Create(ctx, d.writeData(obj, time.Time{}))
+		if status.Code(err) == codes.AlreadyExists {
+			return conflictError("SaveIfUnchanged", collection, id, err)
+		}
+		return wrapError("SaveIfUnchanged", collection, id, err)
+	}
+
+	// Set does not accept preconditions so all fields are replaced using Update
+	// which Firestore rejects with FailedPrecondition when the document changed
+	precond := firestore.LastUpdateTime(lastUpdate)
+	if !d.options().history {
+		_, err := ref.Update(ctx, d.replaceUpdates(obj, time.Time{}), precond)
+		return unchangedError(collection, id, err)
+	}
+
+	err := d.runTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+		if err := d.archive(tx, doc, "SaveIfUnchanged"); err != nil {
+			return err
+		}
+		return tx.Update(ref, d.replaceUpdates(obj, storedTime(doc, createdAtField(obj))), precond)
+	})
+
+	return unchangedError(collection, id, err)
+
+}
+
+// unchangedError reports failed precondition of SaveIfUnchanged as ErrConflict,
+// as well as document which was deleted since lastUpdate
+func unchangedError(collection, id string, err error) error {
+	switch status.Code(err) {
+	case codes.FailedPrecondition, codes.NotFound:
+		return conflictError("SaveIfUnchanged", collection, id, err)
+	}
+	return wrapError("SaveIfUnchanged", collection, id, err)
 }
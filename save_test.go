@@ -0,0 +1,109 @@
+package lighter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreate(t *testing.T) {
+
+	requireStore(t)
+
+	colName := "test_create"
+	ctx := context.Background()
+
+	obj := NewTestObject("John", 40, 2.75)
+	err := store.Create(ctx, colName, obj.ID, obj)
+	assert.Nil(t, err)
+
+	err = store.Create(ctx, colName, obj.ID, obj)
+	assert.True(t, errors.Is(err, ErrAlreadyExists))
+
+	err = store.DeleteByID(ctx, colName, obj.ID)
+	assert.Nil(t, err)
+
+}
+
+func TestSaveIfUnchanged(t *testing.T) {
+
+	requireStore(t)
+
+	colName := "test_saveifunchanged"
+	ctx := context.Background()
+
+	obj := NewTestObject("John", 40, 2.75)
+	err := store.SaveIfUnchanged(ctx, colName, obj.ID, obj, time.Time{})
+	assert.Nil(t, err)
+
+	// zero time means the document must not exist
+	err = store.SaveIfUnchanged(ctx, colName, obj.ID, obj, time.Time{})
+	assert.True(t, errors.Is(err, ErrConflict))
+
+	obj2 := &MockedStoreObject{}
+	updated, err := store.GetByIDWithUpdateTime(ctx, colName, obj.ID, obj2)
+	assert.Nil(t, err)
+	assert.False(t, updated.IsZero())
+
+	obj2.Count++
+	err = store.SaveIfUnchanged(ctx, colName, obj.ID, obj2, updated)
+	assert.Nil(t, err)
+
+	// second writer with the same stale update time
+	obj.Count += 2
+	err = store.SaveIfUnchanged(ctx, colName, obj.ID, obj, updated)
+	assert.True(t, errors.Is(err, ErrConflict))
+
+	err = store.GetByID(ctx, colName, obj.ID, obj2)
+	assert.Nil(t, err)
+	assert.Equal(t, 41, obj2.Count)
+
+	err = store.DeleteByID(ctx, colName, obj.ID)
+	assert.Nil(t, err)
+
+	// deleted document was changed too
+	err = store.SaveIfUnchanged(ctx, colName, obj.ID, obj, updated)
+	assert.True(t, errors.Is(err, ErrConflict))
+
+}
+
+func TestAddValidation(t *testing.T) {
+
+	ctx := context.Background()
+	s := &Store{opts: makeStoreOptions([]StoreOption{
+		WithIDGenerator(func() string { return "1invalid" }),
+	})}
+
+	_, err := s.Add(ctx, "test", nil)
+	assert.NotNil(t, err)
+
+	_, err = s.Add(ctx, "test", &MockedStoreObject{})
+	assert.True(t, errors.Is(err, ErrInvalidID))
+
+}
+
+func TestAdd(t *testing.T) {
+
+	requireStore(t)
+
+	colName := "test_add"
+	ctx := context.Background()
+
+	obj := &MockedStoreObject{Name: "John"}
+	id, err := store.Add(ctx, colName, obj)
+	assert.Nil(t, err)
+	assert.True(t, IsValidID(id))
+	assert.Equal(t, id, obj.ID)
+
+	obj2 := &MockedStoreObject{}
+	err = store.GetByID(ctx, colName, id, obj2)
+	assert.Nil(t, err)
+	assert.Equal(t, id, obj2.ID)
+
+	err = store.DeleteByID(ctx, colName, id)
+	assert.Nil(t, err)
+
+}
@@ -0,0 +1,67 @@
+package lighter
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// DeletedAtField is the document field holding the time when document was
+// marked as deleted by store using soft delete (see WithSoftDelete)
+const DeletedAtField = "deletedAt"
+
+// isHidden reports whether doc is marked as deleted and should be excluded
+// from results of store using soft delete
+func (d *Store) isHidden(doc *firestore.DocumentSnapshot, includeDeleted bool) bool {
+	if includeDeleted || !d.options().softDelete {
+		return false
+	}
+	return isDeleted(doc)
+}
+
+// isDeleted reports whether doc is marked as deleted
+func isDeleted(doc *firestore.DocumentSnapshot) bool {
+	v, err := doc.DataAt(DeletedAtField)
+	return err == nil && v != nil
+}
+
+// Restore removes the deleted mark from document deleted by store using soft delete.
+// Fails with ErrNotFound when the document does not exist
+func (d *Store) Restore(ctx context.Context, collection, id string) error {
+	return d.options().intercept(ctx, Op{Name: "Restore", Collection: collection, ID: id, retry: retryIdempotent}, func(ctx context.Context) error {
+
+		if err := validateDocArgs(collection, id); err != nil {
+			return err
+		}
+
+		return recordWrite(ctx, d.update(ctx, "Restore", collection, id, map[string]interface{}{
+			DeletedAtField: firestore.Delete,
+		}, false))
+
+	})
+}
+
+// PurgeDeleted permanently deletes documents marked as deleted more than
+// olderThan ago and returns the number of purged documents
+func (d *Store) PurgeDeleted(ctx context.Context, collection string, olderThan time.Duration) (purged int, err error) {
+	err = d.options().intercept(ctx, Op{Name: "PurgeDeleted", Collection: collection}, func(ctx context.Context) (err error) {
+		purged, err = d.purgeDeleted(ctx, collection, olderThan)
+		return err
+	})
+	return purged, err
+}
+
+func (d *Store) purgeDeleted(ctx context.Context, collection string, olderThan time.Duration) (purged int, err error) {
+
+	if err := validateCollection(collection); err != nil {
+		return 0, err
+	}
+
+	q := d.client.Collection(collection).Where(DeletedAtField, "<", d.options().clock().Add(-olderThan))
+
+	o := makeDeleteOptions(nil)
+	err = d.deleteRefs(ctx, listQuery(ctx, q), maxBatchWrites, o)
+	return o.deleted, wrapError("PurgeDeleted", collection, "", err)
+
+}
@@ -0,0 +1,152 @@
+package lighter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSoftDelete(t *testing.T) {
+
+	s := newTestStore(t, WithSoftDelete())
+	defer s.Close()
+
+	colName := "test_softdelete"
+	ctx := context.Background()
+
+	obj := NewTestObject("John", 40, 2.75)
+	err := s.Save(ctx, colName, obj.ID, obj)
+	assert.Nil(t, err)
+
+	err = s.DeleteByID(ctx, colName, obj.ID)
+	assert.Nil(t, err)
+
+	// deleting already deleted or missing documents is not an error
+	// and does not change the deleted time
+	deleted, err := store.GetByIDWithUpdateTime(ctx, colName, obj.ID, &MockedStoreObject{})
+	assert.Nil(t, err)
+	assert.Nil(t, s.DeleteByID(ctx, colName, obj.ID))
+	updated, err := store.GetByIDWithUpdateTime(ctx, colName, obj.ID, &MockedStoreObject{})
+	assert.Nil(t, err)
+	assert.Equal(t, deleted, updated)
+	assert.Nil(t, s.DeleteByID(ctx, colName, "missingID"))
+
+	err = s.GetByID(ctx, colName, obj.ID, &MockedStoreObject{})
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	missing, err := s.GetManyByIDs(ctx, colName, []string{obj.ID}, &TestObjectHandler{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{obj.ID}, missing)
+
+	h := &TestObjectHandler{}
+	err = s.GetByQuery(ctx, &QueryCriteria{Collection: colName}, h)
+	assert.Nil(t, err)
+	assert.Len(t, h.Items, 0)
+
+	err = s.GetByQuery(ctx, &QueryCriteria{Collection: colName, IncludeDeleted: true}, h)
+	assert.Nil(t, err)
+	assert.Len(t, h.Items, 1)
+
+	// data is still available to store without soft delete
+	err = store.GetByID(ctx, colName, obj.ID, &MockedStoreObject{})
+	assert.Nil(t, err)
+
+	// marked documents are not updated
+	err = s.UpdateFields(ctx, colName, obj.ID, map[string]interface{}{"name": "Jane"})
+	assert.True(t, errors.Is(err, ErrNotFound))
+	err = s.Increment(ctx, colName, obj.ID, "count", 1)
+	assert.True(t, errors.Is(err, ErrNotFound))
+	err = s.SaveMerge(ctx, colName, obj.ID, map[string]interface{}{"name": "Jane"})
+	assert.True(t, errors.Is(err, ErrNotFound))
+	err = s.RunInTransaction(ctx, func(ctx context.Context, tx *Tx) error {
+		return tx.Update(colName, obj.ID, map[string]interface{}{"name": "Jane"})
+	})
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	err = s.Restore(ctx, colName, obj.ID)
+	assert.Nil(t, err)
+
+	err = s.GetByID(ctx, colName, obj.ID, &MockedStoreObject{})
+	assert.Nil(t, err)
+
+	err = s.DeleteByID(ctx, colName, obj.ID)
+	assert.Nil(t, err)
+
+	purged, err := s.PurgeDeleted(ctx, colName, time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, purged)
+
+	purged, err = s.PurgeDeleted(ctx, colName, -time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, purged)
+
+	err = store.GetByID(ctx, colName, obj.ID, &MockedStoreObject{})
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	// deletion time comes from the store clock
+	clock := newTestClock()
+	cs := newTestStore(t, WithSoftDelete(), WithClock(clock.Now))
+	defer cs.Close()
+	assert.Nil(t, cs.Save(ctx, colName, obj.ID, obj))
+	assert.Nil(t, cs.DeleteByID(ctx, colName, obj.ID))
+	doc, err := cs.client.Collection(colName).Doc(obj.ID).Get(ctx)
+	assert.Nil(t, err)
+	assert.True(t, clock.Now().Equal(storedTime(doc, DeletedAtField)))
+
+	purged, err = cs.PurgeDeleted(ctx, colName, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, purged)
+	clock.Add(time.Second)
+	purged, err = cs.PurgeDeleted(ctx, colName, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, purged)
+
+}
+
+func TestMemoryStoreSoftDelete(t *testing.T) {
+
+	clock := newTestClock()
+	ms := NewMemoryStore(WithSoftDelete(), WithClock(clock.Now))
+
+	colName := "test_softdelete"
+	ctx := context.Background()
+
+	obj := NewTestObject("John", 40, 2.75)
+	assert.Nil(t, ms.Save(ctx, colName, obj.ID, obj))
+	assert.Nil(t, ms.DeleteByID(ctx, colName, obj.ID))
+	assert.Equal(t, clock.Now(), ms.collections[colName][obj.ID][DeletedAtField])
+
+	// deleting already deleted document keeps its deleted time
+	clock.Add(time.Minute)
+	assert.Nil(t, ms.DeleteByID(ctx, colName, obj.ID))
+	assert.Nil(t, ms.DeleteByID(ctx, colName, "missingID"))
+	assert.Equal(t, clock.Now().Add(-time.Minute), ms.collections[colName][obj.ID][DeletedAtField])
+
+	err := ms.GetByID(ctx, colName, obj.ID, &MockedStoreObject{})
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	h := &TestObjectHandler{}
+	assert.Nil(t, ms.GetByQuery(ctx, &QueryCriteria{Collection: colName}, h))
+	assert.Len(t, h.Items, 0)
+	assert.Nil(t, ms.GetByQuery(ctx, &QueryCriteria{Collection: colName, IncludeDeleted: true}, h))
+	assert.Len(t, h.Items, 1)
+
+	assert.Nil(t, ms.Restore(ctx, colName, obj.ID))
+	assert.Nil(t, ms.GetByID(ctx, colName, obj.ID, &MockedStoreObject{}))
+	assert.True(t, errors.Is(ms.Restore(ctx, colName, "missingID"), ErrNotFound))
+
+	assert.Nil(t, ms.DeleteByID(ctx, colName, obj.ID))
+	purged, err := ms.PurgeDeleted(ctx, colName, time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, purged)
+
+	clock.Add(2 * time.Hour)
+	purged, err = ms.PurgeDeleted(ctx, colName, time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, purged)
+	assert.Empty(t, ms.collections[colName])
+
+}
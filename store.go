@@ -5,73 +5,274 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+)
+
+// DocumentStore defines the document operations common to all lighter stores
+type DocumentStore interface {
+	// Save inserts or updates by ID
+	Save(ctx context.Context, collection string, id string, obj interface{}) error
+	// GetByID returns stored object for given ID
+	GetByID(ctx context.Context, collection, id string, in interface{}) error
+	// GetByQuery allows for filtered query using QueryHandler
+	GetByQuery(ctx context.Context, q *QueryCriteria, h ResultHandler) error
+	// DeleteByID deletes stored object for a given ID
+	DeleteByID(ctx context.Context, collection, id string) error
+	// DeleteAll deletes all items in a collection
+	DeleteAll(ctx context.Context, collection string, batchSize int, opts ...DeleteOption) error
+	// Close releases resources held by the store
+	Close() error
+}
+
+var (
+	_ DocumentStore = (*Store)(nil)
+	_ DocumentStore = (*MemoryStore)(nil)
 )
 
 // Store represents simple FireStore helper
 type Store struct {
 	client *firestore.Client
+	opts   *storeOptions
+}
+
+// StoreOption configures Store and its underlying Firestore client
+type StoreOption func(*storeOptions)
+
+type storeOptions struct {
+	projectID       string
+	credentialsFile string
+	credentialsJSON []byte
+	userAgent       string
+	emulatorHost    string
+	clientOptions   []option.ClientOption
+	bulkConcurrency int
+	idGenerator     func() string
+	softDelete      bool
+	clock           func() time.Time
+	serverTime      bool
+	history         bool
+	maxRevisions    int
+	validators      map[string][]ValidatorFunc
+	deleteHooks     map[string]func() interface{}
+	interceptors    []Interceptor
+	retryPolicy     *RetryPolicy
+}
+
+// WithProjectID sets explicit GCP project ID instead of deriving it
+// from the environment or the metadata server
+func WithProjectID(id string) StoreOption {
+	return func(o *storeOptions) {
+		o.projectID = id
+	}
+}
+
+// WithCredentialsFile authenticates client using service account key file
+func WithCredentialsFile(path string) StoreOption {
+	return func(o *storeOptions) {
+		o.credentialsFile = path
+	}
+}
+
+// WithCredentialsJSON authenticates client using service account key content
+func WithCredentialsJSON(json []byte) StoreOption {
+	return func(o *storeOptions) {
+		o.credentialsJSON = json
+	}
+}
+
+// WithUserAgent sets the user agent reported by the Firestore client
+func WithUserAgent(userAgent string) StoreOption {
+	return func(o *storeOptions) {
+		o.userAgent = userAgent
+	}
+}
+
+// WithEmulatorHost connects client to Firestore emulator running on host (e.g. localhost:8080)
+func WithEmulatorHost(host string) StoreOption {
+	return func(o *storeOptions) {
+		o.emulatorHost = host
+	}
+}
+
+// WithBulkConcurrency sets the max number of batches committed concurrently
+// by bulk operations like SaveMany and DeleteMany (default 4)
+func WithBulkConcurrency(n int) StoreOption {
+	return func(o *storeOptions) {
+		o.bulkConcurrency = n
+	}
+}
+
+// WithIDGenerator sets function generating IDs of documents inserted using Add
+// (default GetNewID). Generated IDs must be valid, see IsValidID
+func WithIDGenerator(fn func() string) StoreOption {
+	return func(o *storeOptions) {
+		o.idGenerator = fn
+	}
+}
+
+// WithSoftDelete makes DeleteByID mark documents as deleted using the
+// DeletedAtField instead of removing them. Marked documents are excluded
+// from GetByID, GetManyByIDs and GetByQuery results until restored using Restore.
+// Field updates (e.g. UpdateFields or Increment) and SaveMerge of marked documents
+// fail with ErrNotFound, Save replaces them including the mark. DeleteAll,
+// DeleteMany and DeleteByQuery always delete permanently
+func WithSoftDelete() StoreOption {
+	return func(o *storeOptions) {
+		o.softDelete = true
+	}
+}
+
+// WithHistory makes Save, SaveIfUnchanged and DeleteByID copy the previous
+// state of the document into its HistoryCollection subcollection. When
+// maxRevisions is greater than 0 only that many latest revisions are kept
+func WithHistory(maxRevisions int) StoreOption {
+	return func(o *storeOptions) {
+		o.history = true
+		o.maxRevisions = maxRevisions
+	}
+}
+
+// WithClock sets function returning the current time used to maintain
+// `lighter:"createdAt"` and `lighter:"updatedAt"` fields (default time.Now in UTC)
+func WithClock(fn func() time.Time) StoreOption {
+	return func(o *storeOptions) {
+		o.clock = fn
+	}
+}
+
+// WithServerTime makes writes set `lighter:"updatedAt"` fields to the
+// Firestore server time instead of the time returned by the clock
+func WithServerTime() StoreOption {
+	return func(o *storeOptions) {
+		o.serverTime = true
+	}
+}
+
+// WithClientOptions passes additional options to the Firestore client
+func WithClientOptions(opts ...option.ClientOption) StoreOption {
+	return func(o *storeOptions) {
+		o.clientOptions = append(o.clientOptions, opts...)
+	}
+}
+
+func makeStoreOptions(opts []StoreOption) *storeOptions {
+	o := &storeOptions{
+		bulkConcurrency: defaultBulkConcurrency,
+		idGenerator:     GetNewID,
+		clock:           utcNow,
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+	if o.idGenerator == nil {
+		o.idGenerator = GetNewID
+	}
+	if o.clock == nil {
+		o.clock = utcNow
+	}
+	return o
 }
 
 // NewClient creates new Firestore client with derived project ID
-func NewClient(ctx context.Context) (client *firestore.Client, err error) {
+func NewClient(ctx context.Context, opts ...StoreOption) (client *firestore.Client, err error) {
+	return newClient(ctx, makeStoreOptions(opts))
+}
+
+func newClient(ctx context.Context, o *storeOptions) (client *firestore.Client, err error) {
 
 	if ctx == nil {
 		return nil, errors.New("ctx required")
 	}
 
-	projectID, err := getProjectID()
-	if err != nil {
-		return nil, err
+	var clientOpts []option.ClientOption
+
+	if o.credentialsFile != "" {
+		info, err := os.Stat(o.credentialsFile)
+		if os.IsNotExist(err) || info.IsDir() {
+			return nil, fmt.Errorf("credential file does not exist: %s", o.credentialsFile)
+		}
+		clientOpts = append(clientOpts, option.WithCredentialsFile(o.credentialsFile))
+	}
+
+	if len(o.credentialsJSON) > 0 {
+		clientOpts = append(clientOpts, option.WithCredentialsJSON(o.credentialsJSON))
+	}
+
+	if o.userAgent != "" {
+		clientOpts = append(clientOpts, option.WithUserAgent(o.userAgent))
 	}
 
-	return firestore.NewClient(ctx, projectID)
+	projectID := o.projectID
+
+	if o.emulatorHost != "" {
+		conn, err := grpc.Dial(o.emulatorHost, grpc.WithInsecure(), grpc.WithPerRPCCredentials(emulatorCreds{}))
+		if err != nil {
+			return nil, fmt.Errorf("error dialing emulator on %s: %v", o.emulatorHost, err)
+		}
+		clientOpts = append(clientOpts, option.WithGRPCConn(conn))
+		if projectID == "" {
+			projectID = emulatorProjectID
+		}
+	}
+
+	clientOpts = append(clientOpts, o.clientOptions...)
+
+	if projectID == "" {
+		if projectID, err = getProjectID(); err != nil {
+			return nil, err
+		}
+	}
+
+	return firestore.NewClient(ctx, projectID, clientOpts...)
 
 }
 
 // NewStore configures new client instance
-func NewStore(ctx context.Context) (db *Store, err error) {
+func NewStore(ctx context.Context, opts ...StoreOption) (db *Store, err error) {
+
+	o := makeStoreOptions(opts)
 
-	c, err := NewClient(ctx)
+	c, err := newClient(ctx, o)
 	if err != nil {
 		return nil, fmt.Errorf("error creating client: %v", err)
 	}
 
 	return &Store{
 		client: c,
+		opts:   o,
 	}, nil
 
 }
 
 // NewStoreWithCredentialsFile configures new client instance with credentials file
 func NewStoreWithCredentialsFile(ctx context.Context, path string) (db *Store, err error) {
+	return NewStore(ctx, WithCredentialsFile(path))
+}
 
-	if ctx == nil {
-		return nil, errors.New("ctx required")
-	}
+// NewEmulatorStore configures new client instance connected to Firestore emulator
+// running on host. Unless provided in options, dummy project ID is used
+func NewEmulatorStore(ctx context.Context, host string, opts ...StoreOption) (db *Store, err error) {
 
-	info, err := os.Stat(path)
-	if os.IsNotExist(err) || info.IsDir() {
-		return nil, fmt.Errorf("credential file does not exist: %s", path)
+	if host == "" {
+		return nil, errors.New("emulator host required")
 	}
 
-	projectID, err := getProjectID()
-	if err != nil {
-		return nil, err
-	}
+	return NewStore(ctx, append(opts, WithEmulatorHost(host))...)
 
-	opt := option.WithCredentialsFile(path)
-	c, err := firestore.NewClient(ctx, projectID, opt)
-	if err != nil {
-		return nil, fmt.Errorf("error creating client with %s credential file: %v", path, err)
-	}
-
-	return &Store{
-		client: c,
-	}, nil
+}
 
+// options returns store options, defaults when store was not created using NewStore
+func (d *Store) options() *storeOptions {
+	if d.opts == nil {
+		return makeStoreOptions(nil)
+	}
+	return d.opts
 }
 
 // Close closes client connection
@@ -5,9 +5,11 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/option"
 )
 
 func TestNewStore(t *testing.T) {
+	requireStore(t)
 	store, err := NewStore(context.Background())
 	assert.Nil(t, err)
 	assert.NotNil(t, store)
@@ -19,3 +21,42 @@ func TestNewStoreWithCredentialsAndNoFile(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.Nil(t, store)
 }
+
+func TestNewStoreWithProjectIDAndNoFile(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewStore(ctx, WithProjectID("test"), WithCredentialsFile("no-file"))
+	assert.NotNil(t, err)
+	assert.Nil(t, store)
+}
+
+func TestStoreOptions(t *testing.T) {
+	o := makeStoreOptions([]StoreOption{
+		WithProjectID("test"),
+		WithUserAgent("test-agent"),
+		WithCredentialsJSON([]byte("{}")),
+		WithClientOptions(option.WithEndpoint("localhost:8080")),
+		nil,
+	})
+	assert.Equal(t, "test", o.projectID)
+	assert.Equal(t, "test-agent", o.userAgent)
+	assert.Equal(t, []byte("{}"), o.credentialsJSON)
+	assert.Len(t, o.clientOptions, 1)
+	assert.Equal(t, defaultBulkConcurrency, o.bulkConcurrency)
+
+	o = makeStoreOptions([]StoreOption{WithBulkConcurrency(10), WithSoftDelete()})
+	assert.Equal(t, 10, o.bulkConcurrency)
+	assert.True(t, o.softDelete)
+}
+
+func TestNewEmulatorStore(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := NewEmulatorStore(ctx, "")
+	assert.NotNil(t, err)
+
+	// dial does not block so no emulator is needed to create the store
+	s, err := NewEmulatorStore(ctx, "localhost:8080")
+	assert.Nil(t, err)
+	assert.NotNil(t, s)
+	assert.Nil(t, s.Close())
+}
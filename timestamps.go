@@ -0,0 +1,146 @@
+package lighter
+
+import (
+	"reflect"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+const (
+	// tagCreatedAt sets time field to the time the document was first written
+	tagCreatedAt = "createdAt"
+	// tagUpdatedAt sets time field to the time of every write
+	tagUpdatedAt = "updatedAt"
+)
+
+// utcNow is the default clock
+func utcNow() time.Time {
+	return time.Now().UTC()
+}
+
+// writeData sets the timestamps maintained by lighter on obj and prepares
+// it for write. created is the creation time of the stored document,
+// zero when the document is new or its creation time is unknown
+func (d *Store) writeData(obj interface{}, created time.Time) interface{} {
+	o := d.options()
+	obj = stampTimes(obj, o.clock(), created, o.serverTime)
+	return toWriteData(obj, o.serverTime)
+}
+
+// replaceUpdates is writeData returning updates of all top level fields of obj,
+// its `lighter:"createdAt"` field is left as stored
+func (d *Store) replaceUpdates(obj interface{}, created time.Time) []firestore.Update {
+	o := d.options()
+	obj = stampTimes(obj, o.clock(), created, o.serverTime)
+	return toReplaceUpdates(obj, o.serverTime, createdAtField(obj))
+}
+
+// stampTimes sets the `lighter:"createdAt"` fields of obj to created, or now
+// when both created and the field are zero, and the `lighter:"updatedAt"`
+// fields to now unless the server time is used. Struct values are copied
+// into new pointer so their fields can be set
+func stampTimes(obj interface{}, now, created time.Time, serverTime bool) interface{} {
+	v := reflect.ValueOf(obj)
+	if v.Kind() == reflect.Struct {
+		if !hasLighterTag(v.Type(), tagCreatedAt) && !hasLighterTag(v.Type(), tagUpdatedAt) {
+			return obj
+		}
+		p := reflect.New(v.Type())
+		p.Elem().Set(v)
+		obj, v = p.Interface(), p
+	}
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return obj
+	}
+	v = v.Elem()
+
+	for _, f := range docFields(v.Type()) {
+		var t time.Time
+		switch {
+		case f.lighter[tagCreatedAt]:
+			t = created
+		case f.lighter[tagUpdatedAt] && !serverTime:
+			t = now
+		default:
+			continue
+		}
+
+		fv, err := settableField(v, f.index)
+		if err != nil || !fv.CanSet() || fv.Type() != typeOfTime {
+			continue
+		}
+		if t.IsZero() {
+			if !fv.Interface().(time.Time).IsZero() {
+				continue
+			}
+			t = now
+		}
+		fv.Set(reflect.ValueOf(t))
+	}
+	return obj
+}
+
+// createdAtField returns the Firestore name of the `lighter:"createdAt"`
+// field of obj or empty string when it has none
+func createdAtField(obj interface{}) string {
+	if names := taggedFields(obj, tagCreatedAt); len(names) > 0 {
+		return names[0]
+	}
+	return ""
+}
+
+// taggedFields returns the Firestore names of the fields of obj with lighter tag
+func taggedFields(obj interface{}, tag string) []string {
+	t := reflect.TypeOf(obj)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	var names []string
+	for _, f := range docFields(t) {
+		if f.lighter[tag] {
+			names = append(names, f.name)
+		}
+	}
+	return names
+}
+
+// withTimeFields adds the `lighter:"createdAt"` and `lighter:"updatedAt"`
+// fields of obj to merge fields so that merged writes maintain them too.
+// Empty fields (merge of all fields) are returned as they are
+func withTimeFields(obj interface{}, fields []string) []string {
+	if len(fields) == 0 {
+		return fields
+	}
+	has := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		has[f] = true
+	}
+	out := append([]string{}, fields...)
+	for _, tag := range []string{tagCreatedAt, tagUpdatedAt} {
+		for _, name := range taggedFields(obj, tag) {
+			if !has[name] {
+				out = append(out, name)
+				has[name] = true
+			}
+		}
+	}
+	return out
+}
+
+// storedTime returns the time stored in field of doc or zero time
+// when the document or the field does not exist
+func storedTime(doc *firestore.DocumentSnapshot, field string) time.Time {
+	if field == "" || doc == nil || !doc.Exists() {
+		return time.Time{}
+	}
+	v, err := doc.DataAt(field)
+	if err != nil {
+		return time.Time{}
+	}
+	t, _ := v.(time.Time)
+	return t
+}
@@ -0,0 +1,199 @@
+package lighter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/stretchr/testify/assert"
+)
+
+type stampedObject struct {
+	Name    string    `firestore:"name"`
+	Created time.Time `firestore:"created" lighter:"createdAt"`
+	Updated time.Time `firestore:"updated" lighter:"updatedAt"`
+}
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testClock) Add(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestClock() *testClock {
+	return &testClock{now: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}
+}
+
+func TestStampTimes(t *testing.T) {
+
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	created := now.Add(-time.Hour)
+
+	obj := &stampedObject{}
+	assert.Equal(t, obj, stampTimes(obj, now, time.Time{}, false))
+	assert.Equal(t, now, obj.Created)
+	assert.Equal(t, now, obj.Updated)
+
+	// stored creation time wins over the object one
+	stampTimes(obj, now.Add(time.Minute), created, false)
+	assert.Equal(t, created, obj.Created)
+	assert.Equal(t, now.Add(time.Minute), obj.Updated)
+
+	// object creation time is kept when the stored one is unknown
+	stampTimes(obj, now.Add(time.Hour), time.Time{}, true)
+	assert.Equal(t, created, obj.Created)
+	assert.Equal(t, now.Add(time.Minute), obj.Updated)
+
+	// struct values are copied
+	stamped := stampTimes(stampedObject{}, now, time.Time{}, false)
+	assert.Equal(t, now, stamped.(*stampedObject).Updated)
+
+	// objects without timestamps are left as is
+	other := NewTestObject("John", 1, 0.1)
+	assert.Equal(t, other, stampTimes(other, now, time.Time{}, false))
+
+}
+
+func TestServerTimeWriteData(t *testing.T) {
+
+	obj := &stampedObject{Name: "John"}
+	assert.Equal(t, obj, toWriteData(obj, false))
+
+	m, ok := toWriteData(obj, true).(map[string]interface{})
+	assert.True(t, ok)
+	assert.Equal(t, firestore.ServerTimestamp, m["updated"])
+	assert.Equal(t, "created", createdAtField(obj))
+	assert.Equal(t, "", createdAtField(NewTestObject("John", 1, 0.1)))
+
+}
+
+func TestMemoryStoreTimestamps(t *testing.T) {
+
+	ctx := context.Background()
+	clock := newTestClock()
+	ms := NewMemoryStore(WithClock(clock.Now))
+	col := "test_memory_timestamps"
+	start := clock.Now()
+
+	assert.Nil(t, ms.Save(ctx, col, "s1", &stampedObject{Name: "John"}))
+
+	// saving new object over stored document keeps its creation time
+	clock.Add(time.Hour)
+	obj := &stampedObject{Name: "Jane"}
+	assert.Nil(t, ms.Save(ctx, col, "s1", obj))
+	assert.Equal(t, start, obj.Created)
+	assert.Equal(t, clock.Now(), obj.Updated)
+
+	out := &stampedObject{}
+	assert.Nil(t, ms.GetByID(ctx, col, "s1", out))
+	assert.Equal(t, start, out.Created)
+	assert.Equal(t, clock.Now(), out.Updated)
+
+}
+
+func TestSaveTimestamps(t *testing.T) {
+
+	requireStore(t)
+
+	clock := newTestClock()
+	s := newTestStore(t, WithClock(clock.Now))
+	defer s.Close()
+
+	ctx := context.Background()
+	col := "test_timestamps"
+	start := clock.Now()
+
+	assert.Nil(t, s.Save(ctx, col, "s1", &stampedObject{Name: "John"}))
+
+	clock.Add(time.Hour)
+	obj := &stampedObject{Name: "Jane"}
+	assert.Nil(t, s.Save(ctx, col, "s1", obj))
+	assert.Equal(t, start, obj.Created)
+
+	out := &stampedObject{}
+	assert.Nil(t, s.GetByID(ctx, col, "s1", out))
+	assert.True(t, start.Equal(out.Created))
+	assert.True(t, clock.Now().Equal(out.Updated))
+
+	// server time replaces the clock for updatedAt
+	st := newTestStore(t, WithClock(clock.Now), WithServerTime())
+	defer st.Close()
+	assert.Nil(t, st.Save(ctx, col, "s1", &stampedObject{Name: "Jane"}))
+	assert.Nil(t, st.GetByID(ctx, col, "s1", out))
+	assert.True(t, start.Equal(out.Created))
+	assert.True(t, out.Updated.After(clock.Now()))
+
+	assert.Nil(t, s.DeleteByID(ctx, col, "s1"))
+
+}
+
+func TestWithTimeFields(t *testing.T) {
+	assert.Empty(t, withTimeFields(&stampedObject{}, nil))
+	assert.Equal(t, []string{"name", "created", "updated"}, withTimeFields(&stampedObject{}, []string{"name"}))
+	assert.Equal(t, []string{"updated", "created"}, withTimeFields(stampedObject{}, []string{"updated"}))
+	assert.Equal(t, []string{"name"}, withTimeFields(map[string]interface{}{}, []string{"name"}))
+}
+
+func TestSaveMergeTimestamps(t *testing.T) {
+
+	clock := newTestClock()
+	s := newTestStore(t, WithClock(clock.Now))
+	defer s.Close()
+
+	ctx := context.Background()
+	col := "test_timestamps_merge"
+	start := clock.Now()
+
+	assert.Nil(t, s.SaveMerge(ctx, col, "m1", &stampedObject{Name: "John"}, "name"))
+
+	clock.Add(time.Hour)
+	assert.Nil(t, s.SaveMerge(ctx, col, "m1", &stampedObject{Name: "Jane"}, "name"))
+
+	out := &stampedObject{}
+	assert.Nil(t, s.GetByID(ctx, col, "m1", out))
+	assert.Equal(t, "Jane", out.Name)
+	assert.True(t, start.Equal(out.Created))
+	assert.True(t, clock.Now().Equal(out.Updated))
+
+	assert.Nil(t, s.DeleteByID(ctx, col, "m1"))
+
+}
+
+func TestSaveManyTimestamps(t *testing.T) {
+
+	clock := newTestClock()
+	s := newTestStore(t, WithClock(clock.Now))
+	defer s.Close()
+
+	ctx := context.Background()
+	col := "test_timestamps_many"
+	start := clock.Now()
+
+	assert.Nil(t, s.SaveMany(ctx, col, []*Document{{ID: "m1", Object: &stampedObject{Name: "John"}}}))
+
+	// re-saving existing document with fresh struct keeps its creation time
+	clock.Add(time.Hour)
+	assert.Nil(t, s.SaveMany(ctx, col, []*Document{
+		{ID: "m1", Object: &stampedObject{Name: "Jane"}},
+		{ID: "m2", Object: &stampedObject{Name: "Joe"}},
+	}))
+
+	out := &stampedObject{}
+	assert.Nil(t, s.GetByID(ctx, col, "m1", out))
+	assert.Equal(t, "Jane", out.Name)
+	assert.True(t, start.Equal(out.Created))
+	assert.True(t, clock.Now().Equal(out.Updated))
+
+	assert.Nil(t, s.GetByID(ctx, col, "m2", out))
+	assert.True(t, clock.Now().Equal(out.Created))
+
+	assert.Nil(t, s.DeleteMany(ctx, col, []string{"m1", "m2"}))
+
+}
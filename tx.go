@@ -0,0 +1,402 @@
+package lighter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errReadOnly is returned by Tx writes in read-only transaction
+var errReadOnly = errors.New("write in read-only transaction")
+
+// TxOption configures transaction run by RunInTransaction
+type TxOption func(*txOptions)
+
+type txOptions struct {
+	maxAttempts int
+	readOnly    bool
+}
+
+// TxMaxAttempts sets the max number of times the transaction is run
+// when it fails due to contention (default 5)
+func TxMaxAttempts(n int) TxOption {
+	return func(o *txOptions) {
+		o.maxAttempts = n
+	}
+}
+
+// TxReadOnly runs transaction which only reads documents, all Tx writes fail
+func TxReadOnly() TxOption {
+	return func(o *txOptions) {
+		o.readOnly = true
+	}
+}
+
+// Tx is the transaction passed to the function run by RunInTransaction.
+// Reads see the documents as they were when the transaction started.
+// Writes are committed atomically after the function returns, so unlike
+// in Firestore transactions reads and writes can be mixed in any order.
+// Each operation passes through the store interceptors
+type Tx struct {
+	d        *Store
+	ctx      context.Context
+	tx       *firestore.Transaction
+	readOnly bool
+
+	// docs caches documents read in transaction by path
+	docs map[string]*firestore.DocumentSnapshot
+	// archived holds paths of documents already archived in transaction
+	archived map[string]bool
+	// writes are applied to tx after all reads
+	writes []func(tx *firestore.Transaction) error
+	// written is the number of documents written or deleted
+	written int
+}
+
+// RunInTransaction runs fn in Firestore transaction committed when fn returns nil.
+// When the transaction fails due to contention fn is run again, so it should not
+// have side effects other than using tx. Errors returned by fn are returned as is
+func (d *Store) RunInTransaction(ctx context.Context, fn func(ctx context.Context, tx *Tx) error, opts ...TxOption) error {
+	return d.options().intercept(ctx, Op{Name: "RunInTransaction"}, func(ctx context.Context) error {
+		return d.runInTransaction(ctx, fn, opts...)
+	})
+}
+
+func (d *Store) runInTransaction(ctx context.Context, fn func(ctx context.Context, tx *Tx) error, opts ...TxOption) error {
+
+	if fn == nil {
+		return errors.New("function required")
+	}
+
+	o := &txOptions{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+
+	var fsOpts []firestore.TransactionOption
+	if o.maxAttempts > 0 {
+		fsOpts = append(fsOpts, firestore.MaxAttempts(o.maxAttempts))
+	}
+	if o.readOnly {
+		fsOpts = append(fsOpts, firestore.ReadOnly)
+	}
+
+	var t *Tx
+	var fnErr error
+	err := d.runTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		t = d.newTx(ctx, tx, o.readOnly)
+		if fnErr = fn(ctx, t); fnErr != nil {
+			return fnErr
+		}
+		return t.apply()
+	}, fsOpts...)
+
+	if err != nil {
+		if err == fnErr {
+			return err
+		}
+		return wrapError("RunInTransaction", "", "", err)
+	}
+
+	recordWritten(ctx, t.written)
+	return nil
+
+}
+
+func (d *Store) newTx(ctx context.Context, tx *firestore.Transaction, readOnly bool) *Tx {
+	return &Tx{
+		d:        d,
+		ctx:      ctx,
+		tx:       tx,
+		readOnly: readOnly,
+		docs:     map[string]*firestore.DocumentSnapshot{},
+		archived: map[string]bool{},
+	}
+}
+
+// GetByID returns stored object for given ID
+func (t *Tx) GetByID(collection, id string, in interface{}) error {
+	return t.d.options().intercept(t.ctx, Op{Name: "GetByID", Collection: collection, ID: id}, func(ctx context.Context) error {
+		return t.getByID(ctx, collection, id, in)
+	})
+}
+
+func (t *Tx) getByID(ctx context.Context, collection, id string, in interface{}) error {
+
+	if err := validateDocArgs(collection, id); err != nil {
+		return err
+	}
+
+	doc, err := t.get(collection, id)
+	if err != nil {
+		return wrapError("GetByID", collection, id, err)
+	}
+
+	return t.d.loadDoc(ctx, "GetByID", collection, id, doc, in)
+
+}
+
+// GetByQuery appends all documents matching q to h
+func (t *Tx) GetByQuery(q *QueryCriteria, h ResultHandler) error {
+	return t.d.options().intercept(t.ctx, queryOp("GetByQuery", q), func(ctx context.Context) error {
+		return t.getByQuery(ctx, q, h)
+	})
+}
+
+func (t *Tx) getByQuery(ctx context.Context, q *QueryCriteria, h ResultHandler) error {
+
+	if q == nil {
+		return fmt.Errorf("query required")
+	}
+
+	if h == nil {
+		return fmt.Errorf("handler required")
+	}
+
+	sq, err := GetQueryByCriteria(t.d.client, q)
+	if err != nil {
+		return fmt.Errorf("error building query: %w", err)
+	}
+
+	docs := t.tx.Documents(sq)
+	defer docs.Stop()
+
+	n, err := handleResults(ctx, docs, h, func(doc *firestore.DocumentSnapshot) bool {
+		return t.d.isHidden(doc, q.IncludeDeleted)
+	})
+	recordResults(ctx, n)
+
+	return wrapError("GetByQuery", q.Collection, "", err)
+
+}
+
+// Save inserts or updates by ID
+func (t *Tx) Save(collection, id string, obj interface{}) error {
+	return t.d.options().intercept(t.ctx, Op{Name: "Save", Collection: collection, ID: id}, func(ctx context.Context) error {
+		return t.save(ctx, "Save", collection, id, obj)
+	})
+}
+
+func (t *Tx) save(ctx context.Context, op, collection, id string, obj interface{}) error {
+
+	if err := t.checkWrite(collection, id, obj); err != nil {
+		return err
+	}
+
+	if err := t.d.options().beforeSave(ctx, op, collection, id, obj); err != nil {
+		return err
+	}
+
+	var doc *firestore.DocumentSnapshot
+	field := createdAtField(obj)
+	if field != "" || t.d.options().history {
+		var err error
+		if doc, err = t.get(collection, id); err != nil {
+			return wrapError(op, collection, id, err)
+		}
+		if err := t.archive(doc, op); err != nil {
+			return wrapError(op, collection, id, err)
+		}
+	}
+
+	ref := t.d.client.Collection(collection).Doc(id)
+	data := t.d.writeData(obj, storedTime(doc, field))
+	t.write(func(tx *firestore.Transaction) error {
+		return tx.Set(ref, data)
+	})
+	return nil
+
+}
+
+// Create inserts new object by ID, the transaction fails
+// with ErrAlreadyExists when document with that ID is already stored
+func (t *Tx) Create(collection, id string, obj interface{}) error {
+	return t.d.options().intercept(t.ctx, Op{Name: "Create", Collection: collection, ID: id}, func(ctx context.Context) error {
+		return t.create(ctx, collection, id, obj)
+	})
+}
+
+func (t *Tx) create(ctx context.Context, collection, id string, obj interface{}) error {
+
+	if err := t.checkWrite(collection, id, obj); err != nil {
+		return err
+	}
+
+	if err := t.d.options().beforeSave(ctx, "Create", collection, id, obj); err != nil {
+		return err
+	}
+
+	ref := t.d.client.Collection(collection).Doc(id)
+	data := t.d.writeData(obj, time.Time{})
+	t.write(func(tx *firestore.Transaction) error {
+		return tx.Create(ref, data)
+	})
+	return nil
+
+}
+
+// Update updates only the provided fields of stored document (see UpdateFields).
+// The transaction fails with ErrNotFound when the document does not exist,
+// documents marked as deleted by store using soft delete fail right away
+func (t *Tx) Update(collection, id string, fields map[string]interface{}) error {
+	return t.d.options().intercept(t.ctx, Op{Name: "Update", Collection: collection, ID: id}, func(context.Context) error {
+		return t.update(collection, id, fields)
+	})
+}
+
+func (t *Tx) update(collection, id string, fields map[string]interface{}) error {
+
+	if len(fields) == 0 {
+		return errors.New("fields required")
+	}
+
+	if err := t.checkWrite(collection, id, fields); err != nil {
+		return err
+	}
+
+	if t.d.options().softDelete {
+		doc, err := t.get(collection, id)
+		if err != nil {
+			return wrapError("Update", collection, id, err)
+		}
+		if doc.Exists() && isDeleted(doc) {
+			return notFoundError("Update", collection, id)
+		}
+	}
+
+	ref := t.d.client.Collection(collection).Doc(id)
+	updates := toUpdates(fields)
+	t.write(func(tx *firestore.Transaction) error {
+		return tx.Update(ref, updates)
+	})
+	return nil
+
+}
+
+// DeleteByID deletes stored object for a given ID the same way as Store.DeleteByID
+func (t *Tx) DeleteByID(collection, id string) error {
+	return t.d.options().intercept(t.ctx, Op{Name: "DeleteByID", Collection: collection, ID: id}, func(ctx context.Context) error {
+		return t.deleteByID(ctx, collection, id)
+	})
+}
+
+func (t *Tx) deleteByID(ctx context.Context, collection, id string) error {
+
+	if err := t.checkWrite(collection, id, struct{}{}); err != nil {
+		return err
+	}
+
+	o := t.d.options()
+	newObj := o.deleteHook(collection)
+	ref := t.d.client.Collection(collection).Doc(id)
+
+	if newObj == nil && !o.history && !o.softDelete {
+		t.write(func(tx *firestore.Transaction) error {
+			return tx.Delete(ref)
+		})
+		return nil
+	}
+
+	doc, err := t.get(collection, id)
+	if err != nil {
+		return wrapError("DeleteByID", collection, id, err)
+	}
+	if !doc.Exists() || (o.softDelete && isDeleted(doc)) {
+		return nil
+	}
+
+	if newObj != nil {
+		obj := newObj()
+		if err := doc.DataTo(obj); err != nil {
+			return fmt.Errorf("error parsing data: %v", err)
+		}
+		if err := applyMetadata(obj, snapshotMetadata(doc)); err != nil {
+			return err
+		}
+		if err := beforeDelete(ctx, "DeleteByID", collection, id, obj); err != nil {
+			return err
+		}
+	}
+
+	if err := t.archive(doc, "DeleteByID"); err != nil {
+		return wrapError("DeleteByID", collection, id, err)
+	}
+
+	// deletion time comes from the store clock, the same as in MemoryStore,
+	// so that PurgeDeleted compares it with the same clock
+	deletedAt := o.clock().UTC()
+	t.write(func(tx *firestore.Transaction) error {
+		if o.softDelete {
+			return tx.Update(ref, []firestore.Update{
+				{Path: DeletedAtField, Value: deletedAt},
+			})
+		}
+		return tx.Delete(ref)
+	})
+	return nil
+
+}
+
+// checkWrite validates write arguments
+func (t *Tx) checkWrite(collection, id string, obj interface{}) error {
+	if t.readOnly {
+		return errReadOnly
+	}
+	if obj == nil {
+		return errors.New("object required")
+	}
+	return validateDocArgs(collection, id)
+}
+
+// get reads document in transaction once, missing document
+// is returned as snapshot which does not exist
+func (t *Tx) get(collection, id string) (*firestore.DocumentSnapshot, error) {
+	ref := t.d.client.Collection(collection).Doc(id)
+	if doc, ok := t.docs[ref.Path]; ok {
+		return doc, nil
+	}
+	doc, err := t.tx.Get(ref)
+	if err != nil && status.Code(err) != codes.NotFound {
+		return nil, err
+	}
+	t.docs[ref.Path] = doc
+	return doc, nil
+}
+
+// archive prepares the revision of doc when store uses history,
+// each document is archived at most once per transaction
+func (t *Tx) archive(doc *firestore.DocumentSnapshot, op string) error {
+	if !t.d.options().history || t.archived[doc.Ref.Path] {
+		return nil
+	}
+	a, err := t.d.prepareArchive(t.tx, doc, op)
+	if err != nil || a == nil {
+		return err
+	}
+	t.archived[doc.Ref.Path] = true
+	t.writes = append(t.writes, a.write)
+	return nil
+}
+
+// write queues fn writing single document to transaction
+func (t *Tx) write(fn func(tx *firestore.Transaction) error) {
+	t.writes = append(t.writes, fn)
+	t.written++
+}
+
+// apply adds all queued writes to the transaction
+func (t *Tx) apply() error {
+	for _, fn := range t.writes {
+		if err := fn(t.tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package lighter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errInsufficientFunds = errors.New("insufficient funds")
+
+// transfer moves n from the count of account from to the count of account to
+func transfer(ctx context.Context, s *Store, col, from, to string, n int) error {
+	return s.RunInTransaction(ctx, func(ctx context.Context, tx *Tx) error {
+		src, dst := &MockedStoreObject{}, &MockedStoreObject{}
+		if err := tx.GetByID(col, from, src); err != nil {
+			return err
+		}
+		if err := tx.GetByID(col, to, dst); err != nil {
+			return err
+		}
+		if src.Count < n {
+			return errInsufficientFunds
+		}
+		src.Count -= n
+		dst.Count += n
+		if err := tx.Save(col, from, src); err != nil {
+			return err
+		}
+		return tx.Save(col, to, dst)
+	})
+}
+
+func TestTxArgs(t *testing.T) {
+
+	ctx := context.Background()
+	s := &Store{opts: makeStoreOptions([]StoreOption{WithValidator("orders", countValidator)})}
+	obj := NewTestObject("John", 1, 0.1)
+
+	assert.NotNil(t, s.RunInTransaction(ctx, nil))
+
+	tx := s.newTx(ctx, nil, false)
+	assert.True(t, errors.Is(tx.GetByID("col", "1", obj), ErrInvalidID))
+	assert.NotNil(t, tx.GetByQuery(nil, &TestObjectHandler{}))
+	assert.NotNil(t, tx.Save("col", obj.ID, nil))
+	assert.True(t, errors.Is(tx.Create("", obj.ID, obj), ErrCollectionRequired))
+	assert.NotNil(t, tx.Update("col", obj.ID, nil))
+	assert.True(t, errors.Is(tx.DeleteByID("col", "1"), ErrInvalidID))
+	assert.True(t, errors.Is(tx.Save("orders", "o1", &validatedObject{Name: "a", Count: -1}), ErrValidation))
+	assert.Empty(t, tx.writes)
+
+	tx = s.newTx(ctx, nil, true)
+	assert.Equal(t, errReadOnly, tx.Save("col", obj.ID, obj))
+	assert.Equal(t, errReadOnly, tx.Update("col", obj.ID, map[string]interface{}{"count": 2}))
+	assert.Equal(t, errReadOnly, tx.DeleteByID("col", obj.ID))
+
+}
+
+func TestTxInterceptors(t *testing.T) {
+
+	errDenied := errors.New("denied")
+	ops := make([]Op, 0)
+	s := &Store{opts: makeStoreOptions([]StoreOption{WithInterceptors(
+		func(ctx context.Context, op Op, next func(ctx context.Context) error) error {
+			ops = append(ops, op)
+			return errDenied
+		},
+	)})}
+
+	ctx := context.Background()
+	obj := NewTestObject("John", 1, 0.1)
+	q := &QueryCriteria{Collection: "c"}
+
+	// interceptors can fail operations before they are added to transaction
+	tx := s.newTx(ctx, nil, false)
+	assert.Equal(t, errDenied, tx.GetByID("c", obj.ID, obj))
+	assert.Equal(t, errDenied, tx.GetByQuery(q, &TestObjectHandler{}))
+	assert.Equal(t, errDenied, tx.Save("c", obj.ID, obj))
+	assert.Equal(t, errDenied, tx.Create("c", obj.ID, obj))
+	assert.Equal(t, errDenied, tx.Update("c", obj.ID, map[string]interface{}{"count": 2}))
+	assert.Equal(t, errDenied, tx.DeleteByID("c", obj.ID))
+	assert.Empty(t, tx.writes)
+
+	assert.Equal(t, []Op{
+		{Name: "GetByID", Collection: "c", ID: obj.ID},
+		{Name: "GetByQuery", Collection: "c", Query: q},
+		{Name: "Save", Collection: "c", ID: obj.ID},
+		{Name: "Create", Collection: "c", ID: obj.ID},
+		{Name: "Update", Collection: "c", ID: obj.ID},
+		{Name: "DeleteByID", Collection: "c", ID: obj.ID},
+	}, ops)
+
+}
+
+func TestRunInTransaction(t *testing.T) {
+
+	s := newTestStore(t, WithHistory(0))
+	defer s.Close()
+
+	col := "test_tx"
+	ctx := context.Background()
+
+	a, b := NewTestObject("a", 100, 0), NewTestObject("b", 0, 0)
+	assert.Nil(t, s.Save(ctx, col, a.ID, a))
+	assert.Nil(t, s.Save(ctx, col, b.ID, b))
+
+	// concurrent transfers do not lose updates
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, transfer(ctx, s, col, a.ID, b.ID, 10))
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, errInsufficientFunds, transfer(ctx, s, col, a.ID, b.ID, 1000))
+
+	out := &MockedStoreObject{}
+	assert.Nil(t, s.GetByID(ctx, col, a.ID, out))
+	assert.Equal(t, 50, out.Count)
+	assert.Nil(t, s.GetByID(ctx, col, b.ID, out))
+	assert.Equal(t, 50, out.Count)
+
+	list, err := s.ListRevisions(ctx, col, b.ID)
+	assert.Nil(t, err)
+	assert.Len(t, list, 5)
+
+	// writes are committed only with the transaction
+	err = s.RunInTransaction(ctx, func(ctx context.Context, tx *Tx) error {
+		assert.Nil(t, tx.Create(col, a.ID, a))
+		assert.Nil(t, tx.DeleteByID(col, b.ID))
+		return nil
+	})
+	assert.True(t, errors.Is(err, ErrAlreadyExists))
+	assert.Nil(t, s.GetByID(ctx, col, b.ID, out))
+
+	err = s.RunInTransaction(ctx, func(ctx context.Context, tx *Tx) error {
+		h := &TestObjectHandler{}
+		assert.Nil(t, tx.GetByQuery(&QueryCriteria{Collection: col}, h))
+		assert.Len(t, h.Items, 2)
+		return tx.Update(col, a.ID, map[string]interface{}{"name": "c"})
+	}, TxReadOnly())
+	assert.Equal(t, errReadOnly, err)
+
+	err = s.RunInTransaction(ctx, func(ctx context.Context, tx *Tx) error {
+		return tx.GetByID(col, "missing", out)
+	}, TxMaxAttempts(1))
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+}
@@ -0,0 +1,244 @@
+package lighter
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UpdateFields updates only the provided fields of stored document.
+// Keys are field paths where nested fields are separated by dots (e.g. "address.city").
+// Fails with ErrNotFound when the document does not exist, or is marked as deleted
+// by store using soft delete (the same applies to Increment, AddToSet, RemoveFromSet
+// and SetServerTimestamp)
+func (d *Store) UpdateFields(ctx context.Context, collection, id string, fields map[string]interface{}) error {
+
+	// fields may hold transforms (e.g. firestore.Increment) which are not safe to repeat
+	return d.updateFields(ctx, "UpdateFields", retryNonIdempotent, collection, id, fields)
+}
+
+// Increment atomically adds n (int or float) to the numeric field.
+// Missing field is treated as zero. Fails with ErrNotFound when the document does not exist
+func (d *Store) Increment(ctx context.Context, collection, id, field string, n interface{}) error {
+	return d.updateFields(ctx, "Increment", retryNonIdempotent, collection, id, map[string]interface{}{
+		field: firestore.Increment(n),
+	})
+}
+
+// AddToSet atomically adds to the array field values which are not already in it.
+// Fails with ErrNotFound when the document does not exist
+func (d *Store) AddToSet(ctx context.Context, collection, id, field string, vals ...interface{}) error {
+	return d.updateFields(ctx, "AddToSet", retryIdempotent, collection, id, map[string]interface{}{
+		field: firestore.ArrayUnion(vals...),
+	})
+}
+
+// RemoveFromSet atomically removes all instances of values from the array field.
+// Fails with ErrNotFound when the document does not exist
+func (d *Store) RemoveFromSet(ctx context.Context, collection, id, field string, vals ...interface{}) error {
+	return d.updateFields(ctx, "RemoveFromSet", retryIdempotent, collection, id, map[string]interface{}{
+		field: firestore.ArrayRemove(vals...),
+	})
+}
+
+// SetServerTimestamp sets the field to the Firestore server time.
+// Fails with ErrNotFound when the document does not exist
+func (d *Store) SetServerTimestamp(ctx context.Context, collection, id, field string) error {
+	return d.updateFields(ctx, "SetServerTimestamp", retryIdempotent, collection, id, map[string]interface{}{
+		field: firestore.ServerTimestamp,
+	})
+}
+
+func (d *Store) updateFields(ctx context.Context, op string, retry retryMode, collection, id string, fields map[string]interface{}) error {
+	return d.options().intercept(ctx, Op{Name: op, Collection: collection, ID: id, retry: retry}, func(ctx context.Context) error {
+
+		if len(fields) == 0 {
+			return errors.New("fields required")
+		}
+
+		if err := validateDocArgs(collection, id); err != nil {
+			return err
+		}
+
+		return recordWrite(ctx, d.update(ctx, op, collection, id, fields, d.options().softDelete))
+
+	})
+
+}
+
+// update updates fields of stored document. When skipDeleted is set documents
+// marked as deleted are reported as not found the same way as by GetByID
+func (d *Store) update(ctx context.Context, op, collection, id string, fields map[string]interface{}, skipDeleted bool) error {
+
+	ref := d.client.Collection(collection).Doc(id)
+	updates := toUpdates(fields)
+
+	if !skipDeleted {
+		_, err := ref.Update(ctx, updates)
+		return wrapError(op, collection, id, err)
+	}
+
+	// Update preconditions can not check fields so the deleted
+	// mark is checked in transaction
+	err := d.runTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+		if isDeleted(doc) {
+			return notFoundError(op, collection, id)
+		}
+		return tx.Update(ref, updates)
+	})
+
+	return wrapError(op, collection, id, err)
+
+}
+
+// SaveMerge writes obj merging it with already stored document.
+// When fields (dot separated paths) are provided only those are written,
+// otherwise obj must be a map and all of its fields are merged.
+// The `lighter:"createdAt"` and `lighter:"updatedAt"` fields of obj are
+// maintained the same way as by Save. Document is created when it does not exist.
+// When store uses soft delete documents marked as deleted fail with ErrNotFound
+func (d *Store) SaveMerge(ctx context.Context, collection, id string, obj interface{}, fields ...string) error {
+	return d.options().intercept(ctx, Op{Name: "SaveMerge", Collection: collection, ID: id, retry: retryIdempotent}, func(ctx context.Context) error {
+		return recordWrite(ctx, d.saveMerge(ctx, collection, id, obj, fields...))
+	})
+}
+
+func (d *Store) saveMerge(ctx context.Context, collection, id string, obj interface{}, fields ...string) error {
+
+	if obj == nil {
+		return errors.New("object required")
+	}
+
+	if err := validateDocArgs(collection, id); err != nil {
+		return err
+	}
+
+	if err := d.options().beforeSave(ctx, "SaveMerge", collection, id, obj); err != nil {
+		return err
+	}
+
+	ref := d.client.Collection(collection).Doc(id)
+	opt := toMergeOption(withTimeFields(obj, fields))
+
+	field := createdAtField(obj)
+	softDelete := d.options().softDelete
+	if field == "" && !softDelete {
+		_, err := ref.Set(ctx, d.writeData(obj, time.Time{}), opt)
+		return wrapError("SaveMerge", collection, id, err)
+	}
+
+	// the stored creation time is read in transaction the same way as in Save
+	// so that it is merged back instead of being replaced, documents marked
+	// as deleted are not merged into
+	err := d.runTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if softDelete && doc.Exists() && isDeleted(doc) {
+			return notFoundError("SaveMerge", collection, id)
+		}
+		return tx.Set(ref, d.writeData(obj, storedTime(doc, field)), opt)
+	})
+
+	return wrapError("SaveMerge", collection, id, err)
+
+}
+
+// UpdateOption configures UpdateByID
+type UpdateOption func(*updateOptions)
+
+type updateOptions struct {
+	createIfMissing bool
+}
+
+// CreateIfMissing makes UpdateByID pass new object created by newObj to mutate
+// and save the result when the document does not exist
+func CreateIfMissing() UpdateOption {
+	return func(o *updateOptions) {
+		o.createIfMissing = true
+	}
+}
+
+// UpdateByID loads the stored document into object created by newObj, passes it
+// to mutate and saves the result, all in single transaction which is run again
+// when the document is changed concurrently. Fails with ErrNotFound when the
+// document does not exist unless CreateIfMissing is used. Errors returned by
+// mutate are returned as is and nothing is saved
+func (d *Store) UpdateByID(ctx context.Context, collection, id string, newObj func() interface{}, mutate func(obj interface{}) error, opts ...UpdateOption) error {
+	return d.options().intercept(ctx, Op{Name: "UpdateByID", Collection: collection, ID: id, retry: retryNonIdempotent}, func(ctx context.Context) error {
+		return d.updateByID(ctx, collection, id, newObj, mutate, opts...)
+	})
+}
+
+func (d *Store) updateByID(ctx context.Context, collection, id string, newObj func() interface{}, mutate func(obj interface{}) error, opts ...UpdateOption) error {
+
+	if newObj == nil || mutate == nil {
+		return errors.New("newObj and mutate required")
+	}
+
+	if err := validateDocArgs(collection, id); err != nil {
+		return err
+	}
+
+	o := &updateOptions{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+
+	return d.runInTransaction(ctx, func(ctx context.Context, tx *Tx) error {
+		obj := newObj()
+		err := tx.getByID(ctx, collection, id, obj)
+		if errors.Is(err, ErrNotFound) {
+			if !o.createIfMissing {
+				return notFoundError("UpdateByID", collection, id)
+			}
+			obj = newObj()
+			setDocID(obj, id)
+		} else if err != nil {
+			return err
+		}
+
+		if err := mutate(obj); err != nil {
+			return err
+		}
+		return tx.save(ctx, "UpdateByID", collection, id, obj)
+	})
+
+}
+
+// toUpdates converts map of field paths into sorted Firestore updates
+func toUpdates(fields map[string]interface{}) []firestore.Update {
+	updates := make([]firestore.Update, 0, len(fields))
+	for path, val := range fields {
+		updates = append(updates, firestore.Update{Path: path, Value: val})
+	}
+	sort.Slice(updates, func(i, j int) bool {
+		return updates[i].Path < updates[j].Path
+	})
+	return updates
+}
+
+// toMergeOption converts dot separated field paths into Firestore merge option
+func toMergeOption(fields []string) firestore.SetOption {
+	if len(fields) == 0 {
+		return firestore.MergeAll
+	}
+	paths := make([]firestore.FieldPath, len(fields))
+	for i, f := range fields {
+		paths[i] = strings.Split(f, ".")
+	}
+	return firestore.Merge(paths...)
+}
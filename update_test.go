@@ -0,0 +1,186 @@
+package lighter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToUpdates(t *testing.T) {
+	updates := toUpdates(map[string]interface{}{
+		"name":       "John",
+		"count":      1,
+		"address.id": "a1",
+	})
+	assert.Len(t, updates, 3)
+	assert.Equal(t, "address.id", updates[0].Path)
+	assert.Equal(t, "count", updates[1].Path)
+	assert.Equal(t, "name", updates[2].Path)
+}
+
+func TestUpdateFields(t *testing.T) {
+
+	requireStore(t)
+
+	colName := "test_updatefields"
+	ctx := context.Background()
+
+	obj := NewTestObject("John", 40, 2.75)
+	err := store.UpdateFields(ctx, colName, obj.ID, map[string]interface{}{"count": 41})
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	err = store.Save(ctx, colName, obj.ID, obj)
+	assert.Nil(t, err)
+
+	err = store.UpdateFields(ctx, colName, obj.ID, map[string]interface{}{"count": 41})
+	assert.Nil(t, err)
+
+	obj2 := &MockedStoreObject{}
+	err = store.GetByID(ctx, colName, obj.ID, obj2)
+	assert.Nil(t, err)
+	assert.Equal(t, 41, obj2.Count)
+	assert.Equal(t, obj.Name, obj2.Name)
+
+	err = store.DeleteByID(ctx, colName, obj.ID)
+	assert.Nil(t, err)
+
+}
+
+func TestSaveMerge(t *testing.T) {
+
+	requireStore(t)
+
+	colName := "test_savemerge"
+	ctx := context.Background()
+
+	obj := NewTestObject("John", 40, 2.75)
+	err := store.Save(ctx, colName, obj.ID, obj)
+	assert.Nil(t, err)
+
+	obj.Name = "Jane"
+	obj.Count = 50
+	err = store.SaveMerge(ctx, colName, obj.ID, obj, "name")
+	assert.Nil(t, err)
+
+	err = store.SaveMerge(ctx, colName, obj.ID, map[string]interface{}{"value": 3.5})
+	assert.Nil(t, err)
+
+	obj2 := &MockedStoreObject{}
+	err = store.GetByID(ctx, colName, obj.ID, obj2)
+	assert.Nil(t, err)
+	assert.Equal(t, "Jane", obj2.Name)
+	assert.Equal(t, 40, obj2.Count)
+	assert.Equal(t, 3.5, obj2.Value)
+
+	err = store.DeleteByID(ctx, colName, obj.ID)
+	assert.Nil(t, err)
+
+}
+
+func TestFieldTransforms(t *testing.T) {
+
+	requireStore(t)
+
+	colName := "test_transforms"
+	ctx := context.Background()
+
+	type tagged struct {
+		ID      string    `firestore:"id"`
+		Count   int       `firestore:"count"`
+		Tags    []string  `firestore:"tags"`
+		Updated time.Time `firestore:"updated" lighter:"serverTimestamp"`
+	}
+
+	obj := &tagged{ID: GetNewID(), Tags: []string{"a"}}
+	err := store.Increment(ctx, colName, obj.ID, "count", 1)
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	err = store.Save(ctx, colName, obj.ID, obj)
+	assert.Nil(t, err)
+
+	err = store.Increment(ctx, colName, obj.ID, "count", 2)
+	assert.Nil(t, err)
+
+	err = store.AddToSet(ctx, colName, obj.ID, "tags", "a", "b", "c")
+	assert.Nil(t, err)
+
+	err = store.RemoveFromSet(ctx, colName, obj.ID, "tags", "c")
+	assert.Nil(t, err)
+
+	obj2 := &tagged{}
+	err = store.GetByID(ctx, colName, obj.ID, obj2)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, obj2.Count)
+	assert.Equal(t, []string{"a", "b"}, obj2.Tags)
+	assert.False(t, obj2.Updated.IsZero())
+
+	err = store.SetServerTimestamp(ctx, colName, obj.ID, "updated")
+	assert.Nil(t, err)
+
+	err = store.DeleteByID(ctx, colName, obj.ID)
+	assert.Nil(t, err)
+
+}
+
+func TestUpdateByIDArgs(t *testing.T) {
+
+	ctx := context.Background()
+	s := &Store{}
+	newObj := func() interface{} { return &MockedStoreObject{} }
+	mutate := func(obj interface{}) error { return nil }
+
+	assert.NotNil(t, s.UpdateByID(ctx, "col", "id1", nil, mutate))
+	assert.NotNil(t, s.UpdateByID(ctx, "col", "id1", newObj, nil))
+	assert.True(t, errors.Is(s.UpdateByID(ctx, "col", "1", newObj, mutate), ErrInvalidID))
+
+}
+
+func TestUpdateByID(t *testing.T) {
+
+	requireStore(t)
+
+	colName := "test_updatebyid"
+	ctx := context.Background()
+	id := GetNewID()
+
+	newObj := func() interface{} { return &MockedStoreObject{} }
+	increment := func(obj interface{}) error {
+		obj.(*MockedStoreObject).Count++
+		return nil
+	}
+
+	err := store.UpdateByID(ctx, colName, id, newObj, increment)
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	assert.Nil(t, store.UpdateByID(ctx, colName, id, newObj, increment, CreateIfMissing()))
+
+	// concurrent updates are not lost
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, store.UpdateByID(ctx, colName, id, newObj, increment))
+		}()
+	}
+	wg.Wait()
+
+	out := &MockedStoreObject{}
+	assert.Nil(t, store.GetByID(ctx, colName, id, out))
+	assert.Equal(t, id, out.ID)
+	assert.Equal(t, 6, out.Count)
+
+	errStop := errors.New("stop")
+	err = store.UpdateByID(ctx, colName, id, newObj, func(obj interface{}) error {
+		obj.(*MockedStoreObject).Count = 100
+		return errStop
+	})
+	assert.Equal(t, errStop, err)
+	assert.Nil(t, store.GetByID(ctx, colName, id, out))
+	assert.Equal(t, 6, out.Count)
+
+}
@@ -0,0 +1,47 @@
+package lighter
+
+// Validator can be implemented by saved objects to check their state.
+// Objects failing validation are not written
+type Validator interface {
+	Validate() error
+}
+
+// ValidatorFunc checks object saved into collection registered using WithValidator
+type ValidatorFunc func(obj interface{}) error
+
+// WithValidator registers fn validating all objects saved into collection.
+// Collection is either the full collection path or collection ID matching
+// nested collections of that name (e.g. orders for users/{uid}/orders)
+func WithValidator(collection string, fn ValidatorFunc) StoreOption {
+	return func(o *storeOptions) {
+		if fn == nil {
+			return
+		}
+		if o.validators == nil {
+			o.validators = map[string][]ValidatorFunc{}
+		}
+		o.validators[collection] = append(o.validators[collection], fn)
+	}
+}
+
+// validate runs Validate method of obj and the validators registered
+// for collection, returning the first error wrapped as ErrValidation
+func (o *storeOptions) validate(op, collection, id string, obj interface{}) error {
+
+	if v, ok := obj.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return validationError(op, collection, id, err)
+		}
+	}
+
+	for _, k := range collectionKeys(collection) {
+		for _, fn := range o.validators[k] {
+			if err := fn(obj); err != nil {
+				return validationError(op, collection, id, err)
+			}
+		}
+	}
+
+	return nil
+
+}
@@ -0,0 +1,71 @@
+package lighter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errNameRequired = errors.New("name required")
+
+type validatedObject struct {
+	Name  string `firestore:"name"`
+	Count int    `firestore:"count"`
+}
+
+func (o *validatedObject) Validate() error {
+	if o.Name == "" {
+		return errNameRequired
+	}
+	return nil
+}
+
+func countValidator(obj interface{}) error {
+	if o, ok := obj.(*validatedObject); ok && o.Count < 0 {
+		return errors.New("negative count")
+	}
+	return nil
+}
+
+func TestValidate(t *testing.T) {
+
+	o := makeStoreOptions([]StoreOption{WithValidator("orders", countValidator), WithValidator("orders", nil)})
+
+	err := o.validate("Save", "orders", "o1", &validatedObject{})
+	assert.True(t, errors.Is(err, ErrValidation))
+	assert.True(t, errors.Is(err, errNameRequired))
+
+	assert.Nil(t, o.validate("Save", "orders", "o1", &validatedObject{Name: "a"}))
+	assert.Nil(t, o.validate("Save", "products", "p1", &validatedObject{Name: "a", Count: -1}))
+
+	// validators apply to nested collections by collection ID
+	for _, col := range []string{"orders", Path("users", "u1", "orders")} {
+		err = o.validate("Save", col, "o1", &validatedObject{Name: "a", Count: -1})
+		assert.True(t, errors.Is(err, ErrValidation))
+	}
+
+}
+
+func TestSaveValidation(t *testing.T) {
+
+	ctx := context.Background()
+	opt := WithValidator("orders", countValidator)
+
+	// validation fails before anything is sent to Firestore
+	s := &Store{opts: makeStoreOptions([]StoreOption{opt})}
+	assert.True(t, errors.Is(s.Save(ctx, "orders", "o1", &validatedObject{}), ErrValidation))
+	assert.True(t, errors.Is(s.Create(ctx, "orders", "o1", &validatedObject{}), ErrValidation))
+
+	_, err := s.Add(ctx, "orders", &validatedObject{Name: "a", Count: -1})
+	assert.True(t, errors.Is(err, ErrValidation))
+
+	err = s.SaveMerge(ctx, "orders", "o1", &validatedObject{Name: "a", Count: -1}, "count")
+	assert.True(t, errors.Is(err, ErrValidation))
+
+	ms := NewMemoryStore(opt)
+	assert.True(t, errors.Is(ms.Save(ctx, "orders", "o1", &validatedObject{Name: "a", Count: -1}), ErrValidation))
+	assert.Nil(t, ms.Save(ctx, "orders", "o1", &validatedObject{Name: "a"}))
+
+}